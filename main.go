@@ -2,14 +2,92 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/dave/youtube/upload"
 )
 
 func main() {
+	refresh := flag.Bool("refresh", false, "ignore the cached sheet snapshots and download all sheet data")
+	write := flag.Bool("write", false, "reconcile: write missing youtube_id and playlist_id cells to the sheets")
+	force := flag.Bool("force", false, "overwrite video fields edited in both the sheet and YouTube Studio")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\nCommands:\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "  run                process the expeditions and upload to YouTube (default)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  lint               render every template against every item and report problems\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  funcs              list the template functions with examples\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  reconcile          match the videos and playlists on the channel to the sheets\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  plan [file]        write the changes a run would make to YouTube to a plan file (default plan.json)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  apply <file>       make the changes in a plan file, if nothing has changed since it was made\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  rollback <run-id>  undo the changes a run made to YouTube, from the journal\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.Arg(0) == "funcs" {
+		if err := upload.PrintFuncs(os.Stdout); err != nil {
+			log.Fatalf("Template functions: %v", err)
+		}
+		return
+	}
+
+	ctx := context.Background()
 	service := upload.New("UCFDggPICIlCHp3iOWMYt8cg")
-	if err := service.Start(context.Background()); err != nil {
-		log.Fatalf("Unable to initialise service: %v", err)
+	service.Refresh = *refresh
+	service.Force = *force
+
+	switch flag.Arg(0) {
+	case "", "run":
+		if err := service.Start(ctx); err != nil {
+			if errors.Is(err, upload.ErrQuotaCheckpoint) {
+				log.Printf("Out of quota: %v", err)
+				os.Exit(3)
+			}
+			log.Fatalf("Unable to initialise service: %v", err)
+		}
+	case "lint":
+		if err := service.Lint(ctx); err != nil {
+			log.Fatalf("Lint failed: %v", err)
+		}
+	case "reconcile":
+		if err := service.Reconcile(ctx, *write); err != nil {
+			log.Fatalf("Reconcile failed: %v", err)
+		}
+	case "plan":
+		filename := flag.Arg(1)
+		if filename == "" {
+			filename = "plan.json"
+		}
+		if err := service.MakePlan(ctx, filename); err != nil {
+			log.Fatalf("Plan failed: %v", err)
+		}
+	case "apply":
+		if flag.Arg(1) == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := service.ApplyPlan(ctx, flag.Arg(1)); err != nil {
+			if errors.Is(err, upload.ErrQuotaCheckpoint) {
+				log.Printf("Out of quota: %v", err)
+				os.Exit(3)
+			}
+			log.Fatalf("Apply failed: %v", err)
+		}
+	case "rollback":
+		if flag.Arg(1) == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := service.Rollback(ctx, flag.Arg(1)); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
 	}
 }
@@ -128,7 +128,24 @@ func (s *Service) Initialise(contentFile string, data *youtube.Video) error {
 		return fmt.Errorf("marshaling meta data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://www.googleapis.com/upload/youtube/v3/videos?uploadType=resumable&part=snippet,status", bytes.NewReader(dataBytes))
+	var parts []string
+	if data.Snippet != nil {
+		parts = append(parts, "snippet")
+	}
+	if data.Status != nil {
+		parts = append(parts, "status")
+	}
+	if data.Localizations != nil {
+		parts = append(parts, "localizations")
+	}
+	if data.RecordingDetails != nil {
+		parts = append(parts, "recordingDetails")
+	}
+	if data.PaidProductPlacementDetails != nil {
+		parts = append(parts, "paidProductPlacementDetails")
+	}
+
+	req, err := http.NewRequest("POST", "https://www.googleapis.com/upload/youtube/v3/videos?uploadType=resumable&part="+strings.Join(parts, ","), bytes.NewReader(dataBytes))
 	if err != nil {
 		return fmt.Errorf("creating new http request: %w", err)
 	}
@@ -146,7 +163,9 @@ func (s *Service) Initialise(contentFile string, data *youtube.Video) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to initiate upload, status %d: %v", resp.StatusCode, resp.Status)
+		// the body has the error reason, e.g. quotaExceeded
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to initiate upload, status %d: %v: %s", resp.StatusCode, resp.Status, strings.TrimSpace(string(body)))
 	}
 
 	s.UploadURL = resp.Header.Get("Location")
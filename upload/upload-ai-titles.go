@@ -8,7 +8,6 @@ import (
 	"path"
 	"strings"
 
-	"google.golang.org/api/sheets/v4"
 	"google.golang.org/genai"
 )
 
@@ -18,6 +17,7 @@ func (s *Service) GenerateAiTitles(ctx context.Context) error {
 		return nil
 	}
 
+	var values [][]any
 	for _, expedition := range s.Expeditions {
 		if !expedition.Process {
 			continue
@@ -133,7 +133,6 @@ Here's the data about the videos:
 		if err := json.Unmarshal([]byte(result.Text()), &results); err != nil {
 			return fmt.Errorf("unmarshaling results %#v: %w", result.Text(), err)
 		}
-		var values [][]any
 		for _, resultItem := range results {
 			var item *Item
 			for _, current := range expedition.Items {
@@ -145,7 +144,6 @@ Here's the data about the videos:
 			if item == nil {
 				return fmt.Errorf("no item found for %#v", resultItem)
 			}
-			// headers := []string{"expedition", "type", "key", "title", "thumbnail", "tags", "description"}
 			var value []any
 			value = append(value, item.Expedition.Ref)
 			value = append(value, item.Type)
@@ -156,27 +154,17 @@ Here's the data about the videos:
 			value = append(value, resultItem.Description)
 			values = append(values, value)
 		}
-		// Define the range to append the data
-		rangeToAppend := fmt.Sprintf("%s!A1", "preview_titles")
-
-		// Create a request to append the specified values
-		valueRange := &sheets.ValueRange{
-			Values: values,
-		}
-
-		// Execute the append request
-		_, err = s.SheetsService.Spreadsheets.Values.Append(SPREADSHEET_ID, rangeToAppend, valueRange).
-			ValueInputOption("RAW").
-			InsertDataOption("INSERT_ROWS").
-			Do()
-		if err != nil {
-			return fmt.Errorf("unable to append rows to preview_titles sheet: %w", err)
+		// Write the results for all expeditions so far, so they are saved if a later expedition fails
+		if err := s.writeTab("preview_titles", previewTitlesHeaders, values, "RAW"); err != nil {
+			return fmt.Errorf("unable to write rows to preview_titles sheet: %w", err)
 		}
 	}
 
 	return nil
 }
 
+var previewTitlesHeaders = []string{"expedition", "type", "key", "title", "thumbnail", "tags", "description"}
+
 type GeminiRequest struct {
 	Name        string              `json:"title"`       // expedition.name
 	Description string              `json:"description"` // expedition.description
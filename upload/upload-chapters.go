@@ -0,0 +1,150 @@
+package upload
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// YouTube only creates chapters from the timestamps in a description when these rules are met.
+const (
+	minChapters       = 3
+	minChapterLength  = 10 * time.Second
+	chapterSheetName  = "chapter"
+	chapterTimeColumn = "timestamp"
+)
+
+// Chapter is a row from the expedition chapter sheet. Templates list chapters with
+// {{range .Chapters}}{{.Timestamp}} {{.Label}}{{end}}.
+type Chapter struct {
+	RowId int
+	Item  *Item
+	Time  time.Duration
+	Label string
+	Data  map[string]Cell
+}
+
+// Timestamp formats the chapter time as YouTube expects: "0:00", "12:34" or "1:02:03".
+func (c *Chapter) Timestamp() string {
+	total := int(c.Time / time.Second)
+	hours, minutes, seconds := total/3600, total%3600/60, total%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// ParseChapters reads the chapter sheet of each expedition. The item_ref column matches the ref column in
+// the item sheet. The timestamp column must be plain text, and numbers in it are reported as problems.
+func (s *Service) ParseChapters() error {
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		sheet, ok := expedition.Sheets[chapterSheetName]
+		if !ok {
+			continue
+		}
+
+		itemsByRef := map[string]*Item{}
+		for _, item := range expedition.Items {
+			if ref := item.Data["ref"].String(); ref != "" {
+				itemsByRef[ref] = item
+			}
+		}
+
+		for _, data := range sheet.Data {
+			ref := data["item_ref"].String()
+			if ref == "" {
+				continue
+			}
+			item, ok := itemsByRef[ref]
+			if !ok {
+				// reported when the linked data is parsed
+				continue
+			}
+			// Sheets reads a typed 1:30 as the time 01:30, so it must be plain text to mean 1 minute 30 seconds
+			if _, ok := data[chapterTimeColumn].Value().(string); !ok && !data[chapterTimeColumn].Empty() {
+				s.AddProblem(sheet, data["row_id"].Int(), chapterTimeColumn, item.String(), fmt.Errorf("timestamp is a number or time (%s), format the %s column as plain text", data[chapterTimeColumn].String(), chapterTimeColumn))
+				continue
+			}
+			t, err := data[chapterTimeColumn].Duration()
+			if err != nil {
+				s.AddProblem(sheet, data["row_id"].Int(), chapterTimeColumn, item.String(), err)
+				continue
+			}
+			item.Chapters = append(item.Chapters, &Chapter{
+				RowId: data["row_id"].Int(),
+				Item:  item,
+				Time:  t,
+				Label: data["label"].String(),
+				Data:  data,
+			})
+		}
+
+		for _, item := range expedition.Items {
+			sort.SliceStable(item.Chapters, func(i, j int) bool { return item.Chapters[i].Time < item.Chapters[j].Time })
+		}
+	}
+	return nil
+}
+
+// ValidateChapters checks the chapters against the YouTube rules. The video duration is only known once the
+// video has been uploaded, so timestamps are only checked against it if it's non-zero.
+func (item *Item) ValidateChapters() []ValidationIssue {
+	if len(item.Chapters) == 0 {
+		return nil
+	}
+	var issues []ValidationIssue
+	fail := func(chapter *Chapter, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Field: "chapters", Chapter: chapter, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(item.Chapters) < minChapters {
+		fail(nil, "%d chapters, at least %d are needed", len(item.Chapters), minChapters)
+	}
+	if first := item.Chapters[0]; first.Time != 0 {
+		fail(first, "first chapter starts at %s, it must start at 0:00", first.Timestamp())
+	}
+	for i, chapter := range item.Chapters {
+		if chapter.Label == "" {
+			fail(chapter, "chapter at %s has no label", chapter.Timestamp())
+		}
+		end := item.Duration
+		if i+1 < len(item.Chapters) {
+			end = item.Chapters[i+1].Time
+		}
+		if item.Duration > 0 && chapter.Time >= item.Duration {
+			fail(chapter, "chapter at %s is after the end of the video (%s)", chapter.Timestamp(), formatDuration(item.Duration))
+			continue
+		}
+		if (end > 0 || i+1 < len(item.Chapters)) && end-chapter.Time < minChapterLength {
+			fail(chapter, "chapter at %s is %s long, chapters must be at least %s", chapter.Timestamp(), formatDuration(end-chapter.Time), formatDuration(minChapterLength))
+		}
+	}
+	return issues
+}
+
+var isoDurationRegex = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseYoutubeDuration parses the ISO 8601 duration in the video contentDetails, e.g. "PT1H2M3S".
+func parseYoutubeDuration(s string) (time.Duration, error) {
+	matches := isoDurationRegex.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
@@ -10,10 +10,8 @@ import (
 
 func (s *Service) InitGoogleDriveService() error {
 
-	if s.StorageService != GoogleDriveStorage {
-		return nil
-	}
-
+	// the drive service is also used to check when the spreadsheets were last modified, so is initialised
+	// for all storage services
 	driveService, err := drive.New(s.ServiceAccountClient)
 	if err != nil {
 		return fmt.Errorf("unable to initialise drive service: %w", err)
@@ -81,7 +79,7 @@ func (s *Service) FindGoogleDriveFiles() error {
 
 			if needVideo {
 				videoFilenameRegexBuffer := bytes.NewBufferString("")
-				if err := item.Expedition.Templates.ExecuteTemplate(videoFilenameRegexBuffer, "video_filename", item); err != nil {
+				if err := item.Templates.ExecuteTemplate(videoFilenameRegexBuffer, "video_filename", item); err != nil {
 					return fmt.Errorf("execute video filename regex template (%v): %w", item.String(), err)
 				}
 				videoFilenameRegex, err := regexp.Compile(videoFilenameRegexBuffer.String())
@@ -101,7 +99,7 @@ func (s *Service) FindGoogleDriveFiles() error {
 
 			if needThumbnail {
 				thumbnailFilenameRegexBuffer := bytes.NewBufferString("")
-				if err := item.Expedition.Templates.ExecuteTemplate(thumbnailFilenameRegexBuffer, "thumbnail_filename", item); err != nil {
+				if err := item.Templates.ExecuteTemplate(thumbnailFilenameRegexBuffer, "thumbnail_filename", item); err != nil {
 					return fmt.Errorf("execute thumbnail filename regex template (%v): %w", item.String(), err)
 				}
 				thumbnailFilenameRegex, err := regexp.Compile(thumbnailFilenameRegexBuffer.String())
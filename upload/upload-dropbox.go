@@ -219,7 +219,7 @@ func (s *Service) FindDropboxFiles() error {
 
 			if needVideo {
 				videoFilenameRegexBuffer := bytes.NewBufferString("")
-				if err := item.Expedition.Templates.ExecuteTemplate(videoFilenameRegexBuffer, "video_filename", item); err != nil {
+				if err := item.Templates.ExecuteTemplate(videoFilenameRegexBuffer, "video_filename", item); err != nil {
 					return fmt.Errorf("execute video filename regex template (%v): %w", item.String(), err)
 				}
 				videoFilenameRegex, err := regexp.Compile(videoFilenameRegexBuffer.String())
@@ -239,7 +239,7 @@ func (s *Service) FindDropboxFiles() error {
 
 			if needThumbnail {
 				thumbnailFilenameRegexBuffer := bytes.NewBufferString("")
-				if err := item.Expedition.Templates.ExecuteTemplate(thumbnailFilenameRegexBuffer, "thumbnail_filename", item); err != nil {
+				if err := item.Templates.ExecuteTemplate(thumbnailFilenameRegexBuffer, "thumbnail_filename", item); err != nil {
 					return fmt.Errorf("execute thumbnail filename regex template (%v): %w", item.String(), err)
 				}
 				thumbnailFilenameRegex, err := regexp.Compile(thumbnailFilenameRegexBuffer.String())
@@ -0,0 +1,176 @@
+package upload
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// toLatLon gets the coordinates from a template value: a Location with coordinates, or a route Point.
+func toLatLon(v any) (lat, lon float64, err error) {
+	switch v := v.(type) {
+	case Location:
+		if !v.HasCoordinates {
+			return 0, 0, fmt.Errorf("location %q has no coordinates", v.Name)
+		}
+		return v.Lat, v.Lon, nil
+	case *Location:
+		if v == nil {
+			return 0, 0, fmt.Errorf("location is nil")
+		}
+		return toLatLon(*v)
+	case Point:
+		return v.Lat, v.Lon, nil
+	case *Point:
+		if v == nil {
+			return 0, 0, fmt.Errorf("point is nil")
+		}
+		return v.Lat, v.Lon, nil
+	default:
+		return 0, 0, fmt.Errorf("%T has no coordinates", v)
+	}
+}
+
+// osmLink returns an OpenStreetMap link with a marker at the location.
+func osmLink(v any) (string, error) {
+	lat, lon, err := toLatLon(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://www.openstreetmap.org/?mlat=%.5f&mlon=%.5f#map=14/%.5f/%.5f", lat, lon, lat, lon), nil
+}
+
+// mapsLink returns a Google Maps link to the location.
+func mapsLink(v any) (string, error) {
+	lat, lon, err := toLatLon(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://www.google.com/maps/search/?api=1&query=%.5f,%.5f", lat, lon), nil
+}
+
+// distance returns the great circle distance in metres between two locations or points.
+func distance(a, b any) (float64, error) {
+	lat1, lon1, err := toLatLon(a)
+	if err != nil {
+		return 0, err
+	}
+	lat2, lon2, err := toLatLon(b)
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(haversine(Point{Lat: lat1, Lon: lon1}, Point{Lat: lat2, Lon: lon2})), nil
+}
+
+// parseCoordinates reads the <prefix>_lat and <prefix>_lon columns. ok is false unless both are set and
+// valid. Text that isn't a number, coordinates out of range, and one column set without the other are
+// reported.
+func parseCoordinates(data map[string]Cell, prefix string, report func(column string, err error)) (lat, lon float64, ok bool) {
+	latColumn, lonColumn := prefix+"_lat", prefix+"_lon"
+	if data[latColumn].Empty() && data[lonColumn].Empty() {
+		return 0, 0, false
+	}
+	if data[lonColumn].Empty() {
+		report(lonColumn, fmt.Errorf("%s is set without %s", latColumn, lonColumn))
+		return 0, 0, false
+	}
+	if data[latColumn].Empty() {
+		report(latColumn, fmt.Errorf("%s is set without %s", lonColumn, latColumn))
+		return 0, 0, false
+	}
+	parse := func(column string, limit float64) (float64, bool) {
+		f, err := strconv.ParseFloat(strings.TrimSpace(data[column].String()), 64)
+		if err != nil {
+			report(column, fmt.Errorf("%s %q is not a number", column, data[column].String()))
+			return 0, false
+		}
+		if f < -limit || f > limit {
+			report(column, fmt.Errorf("%s %v is out of range, it must be between %v and %v", column, f, -limit, limit))
+			return 0, false
+		}
+		return f, true
+	}
+	lat, latOk := parse(latColumn, 90)
+	lon, lonOk := parse(lonColumn, 180)
+	return lat, lon, latOk && lonOk
+}
+
+// RecordingLocation returns the location the video was recorded at: the recording_lat / recording_lon
+// columns if they're set, otherwise the from or to location, if it has coordinates. Invalid coordinates
+// are reported when the items are parsed, and aren't used.
+func (item *Item) RecordingLocation() (Location, bool) {
+	if lat, lon, ok := parseCoordinates(item.Data, "recording", func(string, error) {}); ok {
+		return Location{
+			Name:           item.Data["recording_name"].String(),
+			Lat:            lat,
+			Lon:            lon,
+			HasCoordinates: true,
+		}, true
+	}
+	for _, location := range []Location{item.From, item.To} {
+		if location.HasCoordinates {
+			return location, true
+		}
+	}
+	return Location{}, false
+}
+
+// RecordingDate returns the date the video was recorded from the date column, or the zero time.
+func (item *Item) RecordingDate() time.Time {
+	if item.Data["date"].Empty() {
+		return time.Time{}
+	}
+	y, m, d := item.Data["date"].TimeIn(item.TimeZone).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// applyRecordingDetails sets the recording location and date. Fields without data in the sheet are left
+// alone.
+func (y *YoutubeFields) applyRecordingDetails(video *youtube.Video, c *Changes) {
+	c.Recording = Change{Before: formatRecordingDetails(video.RecordingDetails)}
+
+	if y.RecordingLocation != nil || !y.RecordingDate.IsZero() {
+		if video.RecordingDetails == nil {
+			video.RecordingDetails = &youtube.VideoRecordingDetails{}
+		}
+		details := video.RecordingDetails
+		if l := y.RecordingLocation; l != nil {
+			if details.Location == nil || roundCoordinate(details.Location.Latitude) != roundCoordinate(l.Latitude) || roundCoordinate(details.Location.Longitude) != roundCoordinate(l.Longitude) {
+				c.change("recordingDetails")
+				details.Location = l
+			}
+		}
+		if !y.RecordingDate.IsZero() && !youtubeTimeEqual(details.RecordingDate, y.RecordingDate) {
+			c.change("recordingDetails")
+			details.RecordingDate = y.RecordingDate.Format(time.RFC3339)
+		}
+	}
+
+	c.Recording.After = formatRecordingDetails(video.RecordingDetails)
+}
+
+func roundCoordinate(f float64) float64 {
+	return math.Round(f*1e6) / 1e6
+}
+
+func formatRecordingDetails(details *youtube.VideoRecordingDetails) string {
+	if details == nil {
+		return ""
+	}
+	var out string
+	if details.Location != nil {
+		out = fmt.Sprintf("%.6f, %.6f", details.Location.Latitude, details.Location.Longitude)
+	}
+	if details.RecordingDate != "" {
+		if t, err := time.Parse(time.RFC3339, details.RecordingDate); err == nil {
+			out += "\n" + t.Format("2006-01-02")
+		} else {
+			out += "\n" + details.RecordingDate
+		}
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// Before is the state of a resource before an operation changed it. It's written to the journal, so the
+// operation can be rolled back.
+type Before struct {
+	Video        *youtube.Video    `json:"video,omitempty"`
+	Playlist     *youtube.Playlist `json:"playlist,omitempty"`
+	ThumbnailURL string            `json:"thumbnail_url,omitempty"`
+	Thumbnail    []byte            `json:"thumbnail,omitempty"` // downloaded from ThumbnailURL just before it's replaced
+	Synced       map[string]string `json:"synced,omitempty"`    // sync state of the video
+}
+
+// JournalEntry is an operation carried out on YouTube, appended to journal.jsonl.
+type JournalEntry struct {
+	RunId        string                `json:"run_id"`
+	Time         time.Time             `json:"time"`
+	Kind         string                `json:"kind"`
+	Subject      string                `json:"subject"`
+	Target       string                `json:"target,omitempty"`
+	Result       string                `json:"result,omitempty"` // id of the video, playlist or playlist item
+	Parts        []string              `json:"parts,omitempty"`
+	PlaylistItem *youtube.PlaylistItem `json:"playlist_item,omitempty"` // the item inserted, moved or removed
+	Before       *Before               `json:"before,omitempty"`
+}
+
+func journalFilepath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home dir: %w", err)
+	}
+	return path.Join(home, ".config", "wildernessprime", "journal.jsonl"), nil
+}
+
+// copyVideo returns a deep copy of the video, so the before state isn't changed when the video is.
+func copyVideo(video *youtube.Video) (*youtube.Video, error) {
+	data, err := json.Marshal(video)
+	if err != nil {
+		return nil, err
+	}
+	out := &youtube.Video{}
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// copyPlaylist returns a copy of the playlist with its own snippet.
+func copyPlaylist(playlist *youtube.Playlist) *youtube.Playlist {
+	out := *playlist
+	if playlist.Snippet != nil {
+		snippet := *playlist.Snippet
+		out.Snippet = &snippet
+	}
+	return &out
+}
+
+// thumbnailURL returns the URL of the largest thumbnail of the video.
+func thumbnailURL(video *youtube.Video) string {
+	if video.Snippet == nil || video.Snippet.Thumbnails == nil {
+		return ""
+	}
+	thumbnails := video.Snippet.Thumbnails
+	for _, thumbnail := range []*youtube.Thumbnail{thumbnails.Maxres, thumbnails.Standard, thumbnails.High, thumbnails.Medium, thumbnails.Default} {
+		if thumbnail != nil && thumbnail.Url != "" {
+			return thumbnail.Url
+		}
+	}
+	return ""
+}
+
+// captureBefore completes the before state of an operation just before it's carried out: the sync state of
+// the video, and the thumbnail that's about to be replaced.
+func (s *Service) captureBefore(op *Operation) (*Before, error) {
+	before := &Before{}
+	if op.Before != nil {
+		*before = *op.Before
+	}
+	switch op.Kind {
+	case OpVideoUpdate:
+		if err := s.loadSyncState(); err != nil {
+			return nil, err
+		}
+		before.Synced = s.SyncState.Videos[op.Target]
+	case OpThumbnailSet:
+		if before.ThumbnailURL == "" {
+			break
+		}
+		thumbnail, err := downloadThumbnail(before.ThumbnailURL)
+		if err != nil {
+			// the thumbnail is set anyway, and rollback reports that it can't be restored
+			fmt.Printf("Unable to download the previous thumbnail (%v): %v\n", op.Subject, err)
+			break
+		}
+		before.Thumbnail = thumbnail
+	}
+	return before, nil
+}
+
+func downloadThumbnail(url string) ([]byte, error) {
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// journal appends an operation that was carried out to the journal.
+func (s *Service) journal(op *Operation, before *Before, result string) error {
+	if s.RunId == "" {
+		s.RunId = time.Now().UTC().Format("20060102-150405")
+		fmt.Printf("Writing changes to the journal as run %s\n", s.RunId)
+	}
+	entry := &JournalEntry{
+		RunId:        s.RunId,
+		Time:         time.Now(),
+		Kind:         op.Kind,
+		Subject:      op.Subject,
+		Target:       op.Target,
+		Result:       result,
+		Parts:        op.Parts,
+		PlaylistItem: op.PlaylistItem,
+		Before:       before,
+	}
+	if entry.PlaylistItem != nil && op.Kind == OpPlaylistInsert {
+		entry.PlaylistItem.Id = result
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshalling journal entry: %w", err)
+	}
+	filePath, err := journalFilepath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(filePath), 0700); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening journal: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return file.Close()
+}
+
+// readJournal returns the journal entries of a run.
+func readJournal(runId string) ([]*JournalEntry, error) {
+	filePath, err := journalFilepath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	defer file.Close()
+	var entries []*JournalEntry
+	decoder := json.NewDecoder(file)
+	for {
+		entry := &JournalEntry{}
+		if err := decoder.Decode(entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("reading journal: %w", err)
+		}
+		if entry.RunId == runId {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
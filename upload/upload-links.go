@@ -0,0 +1,146 @@
+package upload
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// PendingLinkRegex matches the placeholder rendered by the link functions when the target video or playlist
+// doesn't exist on YouTube yet.
+var PendingLinkRegex = regexp.MustCompile(`\[pending link: ([^\]]*)\]`)
+
+func pendingLink(target string) string {
+	return fmt.Sprintf("[pending link: %s]", target)
+}
+
+// LinkFuncs returns the template functions that link to other videos and playlists. Targets that don't
+// exist are template errors. Targets that exist but haven't been uploaded yet render a pending link
+// placeholder. The item and section sheets of expeditions that aren't being processed are loaded the first
+// time they're linked to.
+func (s *Service) LinkFuncs() template.FuncMap {
+	return template.FuncMap{
+
+		// link returns the URL of the video for an item: {{link "kanch" "day" 7}}
+		"link": func(expeditionRef, itemType string, key any) (string, error) {
+			k, err := toFloat(key)
+			if err != nil {
+				return "", err
+			}
+			target := fmt.Sprintf("%s %s %d", expeditionRef, itemType, int(k))
+			expedition, ok := s.Expeditions[expeditionRef]
+			if !ok {
+				return "", fmt.Errorf("link to %s: expedition %q not found", target, expeditionRef)
+			}
+			if !expedition.Process {
+				// the items of the expedition aren't parsed, so the id is read from its item sheet
+				sheet, err := s.linkedSheet(expedition, "item")
+				if err != nil {
+					return "", fmt.Errorf("link to %s: %w", target, err)
+				}
+				for _, data := range sheet.Data {
+					if data["type"].String() != itemType || data["key"].Int() != int(k) {
+						continue
+					}
+					if !data["video"].Bool() {
+						return "", fmt.Errorf("link to %s: item is not a video", target)
+					}
+					youtubeId := data["youtube_id"].String()
+					if youtubeId == "" || isPendingUpload(youtubeId) {
+						return pendingLink(target), nil
+					}
+					return fmt.Sprintf("https://youtu.be/%s", youtubeId), nil
+				}
+				return "", fmt.Errorf("link to %s: item not found", target)
+			}
+			for _, item := range expedition.Items {
+				if item.Type != itemType || item.Key != int(k) {
+					continue
+				}
+				if !item.Video {
+					return "", fmt.Errorf("link to %s: item is not a video", target)
+				}
+				if item.URL() == "" {
+					return pendingLink(target), nil
+				}
+				return item.URL(), nil
+			}
+			return "", fmt.Errorf("link to %s: item not found", target)
+		},
+
+		// playlistLink returns the URL of an expedition playlist, or of a section playlist if a section is
+		// given: {{playlistLink "ght"}} or {{playlistLink "ght" "s3"}}
+		"playlistLink": func(expeditionRef string, sectionRef ...string) (string, error) {
+			target := strings.Join(append([]string{expeditionRef}, sectionRef...), " ")
+			expedition, ok := s.Expeditions[expeditionRef]
+			if !ok {
+				return "", fmt.Errorf("playlist link to %s: expedition %q not found", target, expeditionRef)
+			}
+			switch len(sectionRef) {
+			case 0:
+				if expedition.URL() == "" {
+					return pendingLink(target), nil
+				}
+				return expedition.URL(), nil
+			case 1:
+				if !expedition.Process {
+					// the sections of the expedition aren't parsed, so the id is read from its section sheet
+					sheet, err := s.linkedSheet(expedition, "section")
+					if err != nil {
+						return "", fmt.Errorf("playlist link to %s: %w", target, err)
+					}
+					data, ok := sheet.DataByRef[sectionRef[0]]
+					if !ok {
+						return "", fmt.Errorf("playlist link to %s: section %q not found", target, sectionRef[0])
+					}
+					if data["playlist_id"].String() == "" {
+						return pendingLink(target), nil
+					}
+					return playlistURL(data["playlist_id"].String()), nil
+				}
+				section, ok := expedition.SectionsByRef[sectionRef[0]]
+				if !ok {
+					return "", fmt.Errorf("playlist link to %s: section %q not found", target, sectionRef[0])
+				}
+				if section.URL() == "" {
+					return pendingLink(target), nil
+				}
+				return section.URL(), nil
+			default:
+				return "", fmt.Errorf("playlist link to %s: too many arguments", target)
+			}
+		},
+	}
+}
+
+// linkedSheet returns a sheet of an expedition that isn't being processed, loading it from the expedition
+// spreadsheet the first time it's linked to.
+func (s *Service) linkedSheet(expedition *Expedition, name string) (*Sheet, error) {
+	if sheet, ok := expedition.Sheets[name]; ok {
+		return sheet, nil
+	}
+	if expedition.Spreadsheet == nil {
+		snapshot, err := s.GetSnapshot(expedition.DataSheetId)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve spreadsheet of expedition %q: %w", expedition.Ref, err)
+		}
+		expedition.Spreadsheet = snapshot.Spreadsheet
+	}
+	if err := s.GetSheetData(expedition, name); err != nil {
+		// the sheet is removed, so the error is returned again for the next link
+		delete(expedition.Sheets, name)
+		if name == "item" {
+			expedition.ItemSheet = nil
+		}
+		return nil, fmt.Errorf("unable to get %s sheet of expedition %q: %w", name, expedition.Ref, err)
+	}
+	return expedition.Sheets[name], nil
+}
+
+// LinkFuncDocs documents the functions from LinkFuncs. The examples need the sheet data, so they aren't
+// executed by PrintFuncs.
+var LinkFuncDocs = []FuncDoc{
+	{"link", "link EXPEDITION TYPE KEY", "Links to the video for an item.", `{{link "kanch" "day" 7}}`, ""},
+	{"playlistLink", "playlistLink EXPEDITION [SECTION]", "Links to an expedition or section playlist.", `{{playlistLink "ght" "s3"}}`, ""},
+}
@@ -0,0 +1,280 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// LintIssue is a template problem found by Lint. The same issue found for several items is reported once,
+// with all the items listed as subjects.
+type LintIssue struct {
+	Warning    bool
+	Expedition string
+	Template   string
+	Message    string
+	Subjects   []string
+}
+
+func (i *LintIssue) String() string {
+	severity := "error"
+	if i.Warning {
+		severity = "warning"
+	}
+	out := fmt.Sprintf("%s: %s: template %q: %s", severity, i.Expedition, i.Template, i.Message)
+	if len(i.Subjects) == 1 {
+		out += fmt.Sprintf(" (%s)", i.Subjects[0])
+	} else if len(i.Subjects) > 1 {
+		out += fmt.Sprintf(" (%d items: %s)", len(i.Subjects), strings.Join(i.Subjects, "; "))
+	}
+	return out
+}
+
+// Lint parses the global and expedition templates, and executes every template that a run would use
+// against every item and playlist, without touching YouTube. Missing Data keys are reported as errors.
+func (s *Service) Lint(ctx context.Context) error {
+
+	if err := s.InitialiseServiceAccount(ctx); err != nil {
+		return fmt.Errorf("init service account: %w", err)
+	}
+	if err := s.InitGoogleDriveService(); err != nil {
+		return fmt.Errorf("init drive service: %w", err)
+	}
+	if err := s.InitSheetsService(); err != nil {
+		return fmt.Errorf("init sheets service: %w", err)
+	}
+	if err := s.LoadSheets(); err != nil {
+		return err
+	}
+
+	var errorCount int
+	for _, p := range s.Problems {
+		fmt.Printf("error: %s: %s: %s\n", p.Expedition, p.Item, p.Message)
+		errorCount++
+	}
+
+	issues, err := s.LintTemplates()
+	if err != nil {
+		return fmt.Errorf("linting templates: %w", err)
+	}
+	for _, issue := range issues {
+		fmt.Println(issue.String())
+		if !issue.Warning {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("lint found %d errors", errorCount)
+	}
+	fmt.Println("No lint errors found")
+	return nil
+}
+
+// LintTemplates executes the templates for all processed expeditions, and returns the issues found.
+func (s *Service) LintTemplates() ([]*LintIssue, error) {
+
+	issues := map[string]*LintIssue{}
+	add := func(warning bool, expedition, templateName, message, subject string) {
+		key := fmt.Sprintf("%v/%s/%s/%s", warning, expedition, templateName, message)
+		if issues[key] == nil {
+			issues[key] = &LintIssue{Warning: warning, Expedition: expedition, Template: templateName, Message: message}
+		}
+		if subject != "" {
+			issues[key].Subjects = append(issues[key].Subjects, subject)
+		}
+	}
+
+	// global templates are only unused if no expedition uses them
+	globalUsed := map[string]bool{}
+	var globalLinted bool
+
+	var refs []string
+	for ref := range s.Expeditions {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	for _, ref := range refs {
+		expedition := s.Expeditions[ref]
+		if !expedition.Process {
+			continue
+		}
+		globalLinted = true
+
+		templates, err := expedition.Templates.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("cloning templates (%v): %w", expedition.Ref, err)
+		}
+		templates.Option("missingkey=error")
+
+		used := map[string]bool{}
+		execute := func(templates *template.Template, templateName string, required bool, data any, subject string) {
+			t := templates.Lookup(templateName)
+			if t == nil {
+				if required {
+					add(false, expedition.Ref, templateName, "template not found", subject)
+				}
+				return
+			}
+			used[templateName] = true
+			if err := t.Execute(io.Discard, data); err != nil {
+				message := err.Error()
+				if strings.Contains(message, "map has no entry for key") {
+					message = "missing Data key: " + message
+				}
+				add(false, expedition.Ref, templateName, message, subject)
+			}
+		}
+
+		for _, item := range expedition.Items {
+			if !item.Video {
+				continue
+			}
+			templates := templates
+			if item.Templates != expedition.Templates {
+				// the item has its own template_<name> overrides
+				templates, err = item.Templates.Clone()
+				if err != nil {
+					return nil, fmt.Errorf("cloning templates (%v): %w", item.String(), err)
+				}
+				templates.Option("missingkey=error")
+			}
+			execute := func(templateName string, required bool) {
+				execute(templates, templateName, required, item, item.String())
+			}
+			if item.Template == "" {
+				add(false, expedition.Ref, "", "item has no template", item.String())
+			} else {
+				execute(item.Template, true)
+			}
+			execute("title", true)
+			execute("video_filename", true)
+			execute("video_title_1", false)
+			execute("video_title_2", false)
+			fields, err := item.youtubeFields()
+			if err != nil {
+				add(false, expedition.Ref, "", err.Error(), item.String())
+			}
+			for _, lang := range item.Languages(fields.DefaultLanguage) {
+				for _, name := range append(item.titleTemplates(lang), item.descriptionTemplates(lang)...) {
+					execute(name, false)
+				}
+			}
+			if item.DoThumbnail {
+				execute("thumbnail_filename", true)
+				execute("thumbnail_top", true)
+				execute("thumbnail_bottom", true)
+			}
+		}
+
+		var parents []HasPlaylist
+		if expedition.ExpeditionPlaylist {
+			parents = append(parents, expedition)
+		}
+		if expedition.SectionPlaylists {
+			for _, section := range expedition.Sections {
+				parents = append(parents, section)
+			}
+		}
+		for _, parent := range parents {
+			data := playlistTemplateData(parent)
+			if _, ok := data["Section"]; !ok {
+				// a missing Section is normal for the expedition playlist
+				data["Section"] = nil
+			}
+			execute(templates, "playlist_title", true, data, parent.String())
+			execute(templates, "playlist_description", true, data, parent.String())
+		}
+
+		// find templates used via {{template "name"}}
+		queue := make([]string, 0, len(used))
+		for name := range used {
+			queue = append(queue, name)
+		}
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			t := templates.Lookup(name)
+			if t == nil || t.Tree == nil {
+				continue
+			}
+			walkTemplateNodes(t.Tree.Root, func(node *parse.TemplateNode) {
+				if templates.Lookup(node.Name) == nil {
+					add(false, expedition.Ref, name, fmt.Sprintf("references missing template %q", node.Name), "")
+					return
+				}
+				if !used[node.Name] {
+					used[node.Name] = true
+					queue = append(queue, node.Name)
+				}
+			})
+		}
+
+		for _, t := range templates.Templates() {
+			if t.Name() == "" {
+				continue
+			}
+			if s.isExpeditionTemplate(expedition, t.Name()) {
+				if !used[t.Name()] {
+					add(true, expedition.Ref, t.Name(), "unused template", "")
+				}
+			} else if used[t.Name()] {
+				// a global definition replaced by an expedition override is kept as "global:<name>"
+				globalUsed[strings.TrimPrefix(t.Name(), GlobalTemplateLevel+":")] = true
+			}
+		}
+
+		for _, override := range expedition.TemplateOverrides {
+			var subject string
+			if override.Item != nil {
+				subject = override.Item.String()
+			}
+			add(true, expedition.Ref, override.Name, override.String(), subject)
+		}
+	}
+
+	if globalLinted {
+		if sheet, ok := s.Sheets["template"]; ok {
+			for _, data := range sheet.Data {
+				ref := data["ref"].String()
+				if data["template"].Empty() || globalUsed[ref] {
+					continue
+				}
+				add(true, "global", ref, "unused template", "")
+			}
+		}
+	}
+
+	var out []*LintIssue
+	for _, issue := range issues {
+		out = append(out, issue)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Warning != out[j].Warning {
+			return !out[i].Warning
+		}
+		if out[i].Expedition != out[j].Expedition {
+			return out[i].Expedition < out[j].Expedition
+		}
+		if out[i].Template != out[j].Template {
+			return out[i].Template < out[j].Template
+		}
+		return out[i].Message < out[j].Message
+	})
+	return out, nil
+}
+
+// isExpeditionTemplate returns true if the template is defined in the expedition template sheet.
+func (s *Service) isExpeditionTemplate(expedition *Expedition, name string) bool {
+	sheet, ok := expedition.Sheets["template"]
+	if !ok {
+		return false
+	}
+	data, ok := sheet.DataByRef[name]
+	return ok && !data["template"].Empty()
+}
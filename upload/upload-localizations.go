@@ -0,0 +1,120 @@
+package upload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// Languages returns the languages that the item is translated into, from the languages setting (e.g.
+// "zh-Hans, de"). The default language of the video is not included.
+func (item *Item) Languages(defaultLanguage string) []string {
+	var out []string
+	seen := map[string]bool{defaultLanguage: true}
+	for _, lang := range splitRefs(item.Setting("languages").String()) {
+		if seen[lang] {
+			continue
+		}
+		seen[lang] = true
+		out = append(out, lang)
+	}
+	return out
+}
+
+// titleTemplates and descriptionTemplates return the template names for a translation, in order of
+// precedence.
+func (item *Item) titleTemplates(lang string) []string {
+	return []string{"title." + lang}
+}
+
+func (item *Item) descriptionTemplates(lang string) []string {
+	return []string{item.Template + "." + lang, "description." + lang}
+}
+
+// localize renders the title and description in a language. A translation column in the item sheet (e.g.
+// "title.de" or "description.de") takes precedence over a template with the same name. A field without a
+// translation uses the default language version. Localize returns false if neither field is translated.
+func (item *Item) localize(lang, title, description string) (youtube.VideoLocalization, bool, error) {
+	localizedTitle, titleFound, err := item.translate("title."+lang, item.titleTemplates(lang))
+	if err != nil {
+		return youtube.VideoLocalization{}, false, err
+	}
+	localizedDescription, descriptionFound, err := item.translate("description."+lang, item.descriptionTemplates(lang))
+	if err != nil {
+		return youtube.VideoLocalization{}, false, err
+	}
+	if !titleFound && !descriptionFound {
+		return youtube.VideoLocalization{}, false, nil
+	}
+	if !titleFound {
+		localizedTitle = title
+	}
+	if !descriptionFound {
+		localizedDescription = description
+	}
+	return youtube.VideoLocalization{Title: localizedTitle, Description: localizedDescription}, true, nil
+}
+
+func (item *Item) translate(column string, templates []string) (string, bool, error) {
+	if !item.Data[column].Empty() {
+		return strings.TrimSpace(item.Data[column].String()), true, nil
+	}
+	for _, name := range templates {
+		if item.Templates.Lookup(name) == nil {
+			continue
+		}
+		buf := &strings.Builder{}
+		if err := item.Templates.ExecuteTemplate(buf, name, item); err != nil {
+			return "", false, fmt.Errorf("error executing template (%v): %w", name, err)
+		}
+		return strings.TrimSpace(buf.String()), true, nil
+	}
+	return "", false, nil
+}
+
+// applyLocalizations updates the configured languages in the video localizations. Localizations for other
+// languages (e.g. added in YouTube Studio) are left alone.
+func (y *YoutubeFields) applyLocalizations(video *youtube.Video, c *Changes) {
+	var langs []string
+	for lang := range y.Localizations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	c.Localizations = map[string]Change{}
+	for _, lang := range langs {
+		localization := y.Localizations[lang]
+		before, exists := video.Localizations[lang]
+		change := Change{}
+		if exists {
+			change.Before = formatLocalization(before)
+		}
+		if !exists || before.Title != localization.Title || before.Description != localization.Description {
+			if video.Localizations == nil {
+				video.Localizations = map[string]youtube.VideoLocalization{}
+			}
+			video.Localizations[lang] = localization
+			c.change("localizations")
+		}
+		change.After = formatLocalization(video.Localizations[lang])
+		c.Localizations[lang] = change
+	}
+}
+
+func formatLocalization(l youtube.VideoLocalization) string {
+	return l.Title + "\n\n" + l.Description
+}
+
+// localizationPreviewPrefix is the prefix of the preview column for each language.
+const localizationPreviewPrefix = "video_localization_"
+
+func (s *Service) storeLocalizationsPreview(item *Item, changes Changes, create bool) {
+	for lang, change := range changes.Localizations {
+		if create {
+			change.Before = ""
+		}
+		s.StoreVideoPreview(item, localizationPreviewPrefix+lang, change.Before, change.After)
+	}
+}
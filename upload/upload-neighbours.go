@@ -0,0 +1,156 @@
+package upload
+
+import "fmt"
+
+// The methods in this file give templates the position of an item in its expedition and section, so
+// descriptions can say "Day 12 of 45" and link to the previous and next videos. Only video items are
+// counted. Prev and Next return nil at the ends, so templates should use {{with .Next}}.
+
+// Prev returns the previous video in the expedition.
+func (item *Item) Prev() *Item {
+	return neighbour(item.Expedition.Videos(), item, -1)
+}
+
+// Next returns the next video in the expedition.
+func (item *Item) Next() *Item {
+	return neighbour(item.Expedition.Videos(), item, 1)
+}
+
+// Index returns the position of the video in the expedition, starting at 1, or 0 if the item isn't a video.
+func (item *Item) Index() int {
+	return position(item.Expedition.Videos(), item)
+}
+
+// Count returns the number of videos in the expedition.
+func (item *Item) Count() int {
+	return len(item.Expedition.Videos())
+}
+
+// SectionPrev returns the previous video in the item's section.
+func (item *Item) SectionPrev() *Item {
+	if item.Section == nil {
+		return nil
+	}
+	return neighbour(item.Section.Videos(), item, -1)
+}
+
+// SectionNext returns the next video in the item's section.
+func (item *Item) SectionNext() *Item {
+	if item.Section == nil {
+		return nil
+	}
+	return neighbour(item.Section.Videos(), item, 1)
+}
+
+// SectionIndex returns the position of the video in its section, starting at 1.
+func (item *Item) SectionIndex() int {
+	if item.Section == nil {
+		return 0
+	}
+	return position(item.Section.Videos(), item)
+}
+
+// SectionCount returns the number of videos in the item's section.
+func (item *Item) SectionCount() int {
+	if item.Section == nil {
+		return 0
+	}
+	return len(item.Section.Videos())
+}
+
+// URL returns the YouTube link for the video, or an empty string if it hasn't been uploaded yet.
+func (item *Item) URL() string {
+	if item.YoutubeId == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://youtu.be/%s", item.YoutubeId)
+}
+
+// Videos returns the video items in the expedition, in sheet order.
+func (e *Expedition) Videos() []*Item {
+	return videos(e.Items)
+}
+
+// URL returns the link to the expedition playlist, or an empty string if it hasn't been created yet.
+func (e *Expedition) URL() string {
+	return playlistURL(e.PlaylistId)
+}
+
+// Videos returns the video items in the section, in sheet order.
+func (s *Section) Videos() []*Item {
+	return videos(s.Items)
+}
+
+// Prev returns the previous section in the expedition.
+func (s *Section) Prev() *Section {
+	if i := s.Index(); i > 1 {
+		return s.Expedition.Sections[i-2]
+	}
+	return nil
+}
+
+// Next returns the next section in the expedition.
+func (s *Section) Next() *Section {
+	if i := s.Index(); i > 0 && i < len(s.Expedition.Sections) {
+		return s.Expedition.Sections[i]
+	}
+	return nil
+}
+
+// Index returns the position of the section in the expedition, starting at 1.
+func (s *Section) Index() int {
+	for i, section := range s.Expedition.Sections {
+		if section == s {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Count returns the number of sections in the expedition.
+func (s *Section) Count() int {
+	return len(s.Expedition.Sections)
+}
+
+// URL returns the link to the section playlist, or an empty string if it hasn't been created yet.
+func (s *Section) URL() string {
+	return playlistURL(s.PlaylistId)
+}
+
+func playlistURL(id string) string {
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://www.youtube.com/playlist?list=%s", id)
+}
+
+func videos(items []*Item) []*Item {
+	var out []*Item
+	for _, item := range items {
+		if item.Video {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func position(items []*Item, item *Item) int {
+	for i, v := range items {
+		if v == item {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func neighbour(items []*Item, item *Item, offset int) *Item {
+	i := position(items, item)
+	if i == 0 {
+		return nil
+	}
+	i = i - 1 + offset
+	if i < 0 || i >= len(items) {
+		return nil
+	}
+	return items[i]
+}
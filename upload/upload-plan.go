@@ -0,0 +1,674 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dave/youtube/resume"
+	"google.golang.org/api/youtube/v3"
+)
+
+const planVersion = 1
+
+// Operation kinds. Every change made to YouTube is an operation, so a plan lists exactly what a run would do.
+const (
+	OpVideoCreate    = "video.create"
+	OpVideoUpdate    = "video.update"
+	OpVideoLink      = "video.link" // writes the id of a video uploaded by an earlier run to the sheet
+	OpThumbnailSet   = "thumbnail.set"
+	OpPlaylistCreate = "playlist.create"
+	OpPlaylistUpdate = "playlist.update"
+	OpPlaylistDelete = "playlist.delete"
+	OpPlaylistInsert = "playlist.insert" // inserts a video into a playlist
+	OpPlaylistMove   = "playlist.move"   // moves a playlist item to its position
+	OpPlaylistRemove = "playlist.remove" // removes a playlist item
+)
+
+// Plan is the list of operations a run would carry out, written by the plan command and carried out by
+// the apply command.
+type Plan struct {
+	Version    int          `json:"version"`
+	Created    time.Time    `json:"created"`
+	Operations []*Operation `json:"operations"`
+}
+
+// Operation is a change to YouTube. Operations are carried out straight away in a normal run, and recorded
+// in the plan by the plan command.
+type Operation struct {
+	Id         int               `json:"id"`
+	Kind       string            `json:"kind"`
+	Subject    string            `json:"subject"`          // the item, expedition or section
+	Target     string            `json:"target,omitempty"` // id of the video, playlist or playlist item, or a planned id
+	Parts      []string          `json:"parts,omitempty"`
+	Changes    map[string]Change `json:"changes,omitempty"`     // before and after values, for review
+	SourceHash string            `json:"source_hash,omitempty"` // hash of the rendered fields
+	LiveHash   string            `json:"live_hash,omitempty"`   // hash of the live resource when planned
+
+	Video        *youtube.Video        `json:"video,omitempty"`
+	Playlist     *youtube.Playlist     `json:"playlist,omitempty"`
+	PlaylistItem *youtube.PlaylistItem `json:"playlist_item,omitempty"`
+	ForceSend    map[string][]string   `json:"force_send,omitempty"` // ForceSendFields, which aren't marshalled
+	File         string                `json:"file,omitempty"`       // storage file id of the video to upload
+	Thumbnail    []byte                `json:"thumbnail,omitempty"`
+	Cell         *CellRef              `json:"cell,omitempty"`   // set to the new id, or cleared when a playlist is deleted
+	Synced       map[string]string     `json:"synced,omitempty"` // sync state recorded when the video is updated
+	Before       *Before               `json:"before,omitempty"` // state before the operation, for rollback
+
+	Done   bool   `json:"done,omitempty"`
+	Result string `json:"result,omitempty"` // id of the created video, playlist or playlist item
+}
+
+// CellRef is a cell in the sheets that an operation writes to.
+type CellRef struct {
+	Expedition string `json:"expedition,omitempty"` // empty for the expedition sheet
+	Sheet      string `json:"sheet"`
+	RowId      int    `json:"row_id"`
+	Column     string `json:"column"`
+	Before     string `json:"before"` // value when planned
+}
+
+func (op *Operation) String() string {
+	return fmt.Sprintf("%d %s (%s)", op.Id, op.Kind, op.Subject)
+}
+
+// plannedId is the id of a video, playlist or playlist item that will be created by an operation. It's
+// replaced with the real id when the plan is applied, including in the descriptions of other videos.
+func plannedId(id int) string {
+	return fmt.Sprintf("PLANNED_%d_", id)
+}
+
+func isPlannedId(id string) bool {
+	return strings.HasPrefix(id, "PLANNED_")
+}
+
+// hashJSON returns a short hash of the JSON encoding of v.
+func hashJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:hashLength], nil
+}
+
+// liveVideoHash hashes the updatable parts of a video, as read from YouTube.
+func liveVideoHash(video *youtube.Video) (string, error) {
+	return hashJSON([]any{video.Snippet, video.Localizations, video.Status, video.RecordingDetails, video.PaidProductPlacementDetails})
+}
+
+// livePlaylistItemsHash hashes the order of the items in a playlist.
+func livePlaylistItemsHash(items []*youtube.PlaylistItem) (string, error) {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.Id+":"+item.Snippet.ResourceId.VideoId)
+	}
+	return hashJSON(ids)
+}
+
+// captureForceSend keeps the ForceSendFields of the payload, so they survive the plan file.
+func (op *Operation) captureForceSend() {
+	op.ForceSend = map[string][]string{}
+	if op.Video != nil && op.Video.Status != nil {
+		op.ForceSend["status"] = op.Video.Status.ForceSendFields
+	}
+	if op.Video != nil && op.Video.PaidProductPlacementDetails != nil {
+		op.ForceSend["paidProductPlacementDetails"] = op.Video.PaidProductPlacementDetails.ForceSendFields
+	}
+	if op.PlaylistItem != nil && op.PlaylistItem.Snippet != nil {
+		op.ForceSend["snippet"] = op.PlaylistItem.Snippet.ForceSendFields
+	}
+}
+
+func (op *Operation) restoreForceSend() {
+	if op.Video != nil && op.Video.Status != nil {
+		op.Video.Status.ForceSendFields = op.ForceSend["status"]
+	}
+	if op.Video != nil && op.Video.PaidProductPlacementDetails != nil {
+		op.Video.PaidProductPlacementDetails.ForceSendFields = op.ForceSend["paidProductPlacementDetails"]
+	}
+	if op.PlaylistItem != nil && op.PlaylistItem.Snippet != nil {
+		op.PlaylistItem.Snippet.ForceSendFields = op.ForceSend["snippet"]
+	}
+}
+
+// clone returns a deep copy of the operation, with the planned ids replaced by the real ids.
+func (op *Operation) clone(ids map[string]string) (*Operation, error) {
+	b, err := json.Marshal(op)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling operation: %w", err)
+	}
+	data := string(b)
+	for planned, id := range ids {
+		data = strings.ReplaceAll(data, planned, id)
+	}
+	out := &Operation{}
+	if err := json.Unmarshal([]byte(data), out); err != nil {
+		return nil, fmt.Errorf("unmarshalling operation: %w", err)
+	}
+	return out, nil
+}
+
+// execute carries out an operation, or records it in the plan if a plan is being made. It returns the id of
+// the video, playlist or playlist item, which is a planned id when planning.
+func (s *Service) execute(ctx context.Context, op *Operation) (string, error) {
+	op.captureForceSend()
+	if s.Planning != nil {
+		// copied, so later changes to the resources (e.g. in the second pass) don't change the plan
+		planned, err := op.clone(nil)
+		if err != nil {
+			return "", err
+		}
+		planned.Id = len(s.Planning.Operations) + 1
+		s.Planning.Operations = append(s.Planning.Operations, planned)
+		fmt.Printf("Planned %v\n", planned)
+		return plannedId(planned.Id), nil
+	}
+	return s.run(ctx, op)
+}
+
+// run carries out an operation, and writes it to the journal.
+func (s *Service) run(ctx context.Context, op *Operation) (string, error) {
+	op.restoreForceSend()
+	before, err := s.captureBefore(op)
+	if err != nil {
+		return "", fmt.Errorf("capturing state before %s: %w", op.Kind, err)
+	}
+	id, err := s.call(ctx, op)
+	if err != nil {
+		return "", err
+	}
+	if err := s.journal(op, before, id); err != nil {
+		return "", fmt.Errorf("writing journal: %w", err)
+	}
+	return id, nil
+}
+
+// call makes the API calls for an operation.
+func (s *Service) call(ctx context.Context, op *Operation) (string, error) {
+	switch op.Kind {
+	case OpVideoCreate:
+		// don't start an upload without enough quota left for its thumbnail
+		if err := s.reserve("videos.insert", "thumbnails.set"); err != nil {
+			return "", err
+		}
+		res, err := s.getResume()
+		if err != nil {
+			return "", fmt.Errorf("getting uploader: %w", err)
+		}
+		if res.State == resume.StateUploadInProgress {
+			return "", fmt.Errorf("upload already in progress")
+		}
+		if !isPendingUpload(op.Cell.Before) {
+			if err := s.setCell(op.Cell, PendingUploadMarker); err != nil {
+				return "", fmt.Errorf("setting pending upload marker: %w", err)
+			}
+		}
+		fmt.Printf("Uploading video (%s)\n", op.Subject)
+		progress := func(start int64) {
+			fmt.Printf(" - uploaded %d of %d bytes (%.2f%%)\n", start, res.ContentLength, float64(start)/float64(res.ContentLength)*100)
+		}
+		if err := s.spend("videos.insert"); err != nil {
+			return "", err
+		}
+		if err := res.Initialise(op.File, op.Video); err != nil {
+			return "", fmt.Errorf("initialising upload: %w", err)
+		}
+		insertedVideo, err := res.Upload(ctx, progress)
+		if err != nil {
+			return "", fmt.Errorf("uploading video: %w", err)
+		}
+		// replaces the pending upload marker
+		if err := s.setCell(op.Cell, insertedVideo.Id); err != nil {
+			return "", fmt.Errorf("setting youtube_id: %w", err)
+		}
+		if err := s.recordSync(insertedVideo.Id, op.Synced); err != nil {
+			return "", fmt.Errorf("recording sync state: %w", err)
+		}
+		op.Video = insertedVideo
+		return insertedVideo.Id, nil
+
+	case OpVideoUpdate:
+		fmt.Printf("Updating video %s (%v)\n", strings.Join(op.Parts, ", "), op.Subject)
+		if err := s.spend("videos.update"); err != nil {
+			return "", err
+		}
+		if _, err := s.YoutubeService.Videos.Update(op.Parts, op.Video).Do(); err != nil {
+			return "", fmt.Errorf("updating video: %w", err)
+		}
+		if err := s.recordSync(op.Target, op.Synced); err != nil {
+			return "", fmt.Errorf("recording sync state: %w", err)
+		}
+		return op.Target, nil
+
+	case OpVideoLink:
+		fmt.Printf("Linking uploaded video %s (%v)\n", op.Target, op.Subject)
+		if err := s.setCell(op.Cell, op.Target); err != nil {
+			return "", fmt.Errorf("setting youtube_id: %w", err)
+		}
+		return op.Target, nil
+
+	case OpThumbnailSet:
+		if err := s.spend("thumbnails.set"); err != nil {
+			return "", err
+		}
+		if _, err := s.YoutubeService.Thumbnails.Set(op.Target).Media(bytes.NewReader(op.Thumbnail)).Do(); err != nil {
+			return "", fmt.Errorf("setting thumbnail: %w", err)
+		}
+		return op.Target, nil
+
+	case OpPlaylistCreate:
+		fmt.Printf("Creating playlist (%v)\n", op.Subject)
+		if err := s.spend("playlists.insert"); err != nil {
+			return "", err
+		}
+		newPlaylist, err := s.YoutubeService.Playlists.Insert([]string{"snippet", "status"}, op.Playlist).Do()
+		if err != nil {
+			return "", fmt.Errorf("creating playlist: %w", err)
+		}
+		if err := s.setCell(op.Cell, newPlaylist.Id); err != nil {
+			return "", fmt.Errorf("setting playlist_id: %w", err)
+		}
+		op.Playlist = newPlaylist
+		return newPlaylist.Id, nil
+
+	case OpPlaylistUpdate:
+		if err := s.spend("playlists.update"); err != nil {
+			return "", err
+		}
+		if _, err := s.YoutubeService.Playlists.Update(op.Parts, op.Playlist).Do(); err != nil {
+			return "", fmt.Errorf("updating playlist: %w", err)
+		}
+		return op.Target, nil
+
+	case OpPlaylistDelete:
+		fmt.Println("Deleting playlist", op.Target)
+		if err := s.spend("playlists.delete"); err != nil {
+			return "", err
+		}
+		if err := s.YoutubeService.Playlists.Delete(op.Target).Do(); err != nil {
+			return "", fmt.Errorf("deleting playlist: %w", err)
+		}
+		if err := s.clearCell(op.Cell); err != nil {
+			return "", fmt.Errorf("clearing playlist_id: %w", err)
+		}
+		return op.Target, nil
+
+	case OpPlaylistInsert:
+		fmt.Printf("Inserting playlist item: %s\n", op.PlaylistItem.Snippet.ResourceId.VideoId)
+		if err := s.spend("playlistItems.insert"); err != nil {
+			return "", err
+		}
+		playlistItem, err := s.YoutubeService.PlaylistItems.Insert([]string{"snippet"}, op.PlaylistItem).Do()
+		if err != nil {
+			return "", fmt.Errorf("inserting playlist item %s: %w", op.PlaylistItem.Snippet.ResourceId.VideoId, err)
+		}
+		return playlistItem.Id, nil
+
+	case OpPlaylistMove:
+		if err := s.spend("playlistItems.update"); err != nil {
+			return "", err
+		}
+		if _, err := s.YoutubeService.PlaylistItems.Update([]string{"snippet"}, op.PlaylistItem).Do(); err != nil {
+			return "", fmt.Errorf("moving playlist item %s: %w", op.Target, err)
+		}
+		return op.Target, nil
+
+	case OpPlaylistRemove:
+		fmt.Printf("Deleting playlist item: %s\n", op.PlaylistItem.Snippet.ResourceId.VideoId)
+		if err := s.spend("playlistItems.delete"); err != nil {
+			return "", err
+		}
+		if err := s.YoutubeService.PlaylistItems.Delete(op.Target).Do(); err != nil {
+			return "", fmt.Errorf("deleting playlist item %s: %w", op.Target, err)
+		}
+		return op.Target, nil
+	}
+	return "", fmt.Errorf("unknown operation %s", op.Kind)
+}
+
+// cellRef returns a reference to a cell, with its current value.
+func cellRef(sheet *Sheet, rowId int, column string) *CellRef {
+	cell := &CellRef{Sheet: sheet.Name, RowId: rowId, Column: column}
+	if sheet.Expedition != nil {
+		cell.Expedition = sheet.Expedition.Ref
+	}
+	for _, data := range sheet.Data {
+		if data["row_id"].Int() == rowId {
+			cell.Before = data[column].String()
+		}
+	}
+	return cell
+}
+
+// cellSheet returns the sheet that a cell is in.
+func (s *Service) cellSheet(cell *CellRef) (*Sheet, error) {
+	var sheet *Sheet
+	if cell.Expedition == "" {
+		sheet = s.Sheets[cell.Sheet]
+	} else if expedition, ok := s.Expeditions[cell.Expedition]; ok {
+		sheet = expedition.Sheets[cell.Sheet]
+	}
+	if sheet == nil {
+		return nil, fmt.Errorf("sheet %s not found (%s)", cell.Sheet, cell.Expedition)
+	}
+	return sheet, nil
+}
+
+// cellValue returns the value of the cell, as loaded at the start of the run.
+func (s *Service) cellValue(cell *CellRef) (string, error) {
+	sheet, err := s.cellSheet(cell)
+	if err != nil {
+		return "", err
+	}
+	for _, data := range sheet.Data {
+		if data["row_id"].Int() == cell.RowId {
+			return data[cell.Column].String(), nil
+		}
+	}
+	return "", fmt.Errorf("row %d not found in %s", cell.RowId, cell.Sheet)
+}
+
+func (s *Service) setCell(cell *CellRef, value string) error {
+	sheet, err := s.cellSheet(cell)
+	if err != nil {
+		return err
+	}
+	return sheet.Set(s.SheetsService, cell.RowId, cell.Column, value, true)
+}
+
+func (s *Service) clearCell(cell *CellRef) error {
+	sheet, err := s.cellSheet(cell)
+	if err != nil {
+		return err
+	}
+	return sheet.Clear(s.SheetsService, cell.RowId, cell.Column)
+}
+
+// MakePlan runs everything as a production run would, but writes the changes to YouTube to the plan file
+// instead of making them.
+func (s *Service) MakePlan(ctx context.Context, filename string) error {
+	s.Planning = &Plan{Version: planVersion, Created: time.Now()}
+	if err := s.Start(ctx); err != nil {
+		if isQuotaError(err) {
+			return fmt.Errorf("out of quota after planning %d operations, the plan is incomplete and wasn't written, make the plan again after the quota resets: %w", len(s.Planning.Operations), err)
+		}
+		return err
+	}
+	data, err := json.MarshalIndent(s.Planning, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshalling plan: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	counts := map[string]int{}
+	for _, op := range s.Planning.Operations {
+		counts[op.Kind]++
+	}
+	fmt.Printf("Wrote %d operations to %s\n", len(s.Planning.Operations), filename)
+	for _, kind := range []string{OpVideoCreate, OpVideoUpdate, OpVideoLink, OpThumbnailSet, OpPlaylistCreate, OpPlaylistUpdate, OpPlaylistDelete, OpPlaylistInsert, OpPlaylistMove, OpPlaylistRemove} {
+		if counts[kind] > 0 {
+			fmt.Printf(" - %s: %d\n", kind, counts[kind])
+		}
+	}
+	return nil
+}
+
+// ApplyPlan carries out the operations in a plan file. It refuses if anything the plan changes has changed
+// since the plan was made. Operations are marked as done in the plan file, so if the run stops (e.g. when the
+// quota runs out), applying the plan again continues from where it stopped.
+func (s *Service) ApplyPlan(ctx context.Context, filename string) (err error) {
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("reading plan: %w", err)
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return fmt.Errorf("unmarshalling plan: %w", err)
+	}
+	if plan.Version != planVersion {
+		return fmt.Errorf("plan version %d is not supported, make the plan again", plan.Version)
+	}
+
+	if err := s.InitialiseServiceAccount(ctx); err != nil {
+		return fmt.Errorf("init service account: %w", err)
+	}
+	if err := s.InitialiseYoutubeAuthentication(ctx); err != nil {
+		return fmt.Errorf("init youtube auth: %w", err)
+	}
+	if err := s.InitGoogleDriveService(); err != nil {
+		return fmt.Errorf("init drive service: %w", err)
+	}
+	if err := s.InitDropboxService(ctx); err != nil {
+		return fmt.Errorf("init dropbox service: %w", err)
+	}
+	if err := s.InitSheetsService(); err != nil {
+		return fmt.Errorf("init sheets service: %w", err)
+	}
+	if err := s.LoadSheets(); err != nil {
+		return err
+	}
+
+	drifted, err := s.verifyPlan(plan)
+	if err != nil {
+		return fmt.Errorf("checking plan: %w", err)
+	}
+	if len(drifted) > 0 {
+		for _, message := range drifted {
+			fmt.Println(message)
+		}
+		return fmt.Errorf("%d changes since the plan was made, make the plan again", len(drifted))
+	}
+
+	save := func() error {
+		data, err := json.MarshalIndent(plan, "", "\t")
+		if err != nil {
+			return fmt.Errorf("marshalling plan: %w", err)
+		}
+		if err := os.WriteFile(filename, data, 0600); err != nil {
+			return fmt.Errorf("writing plan: %w", err)
+		}
+		return nil
+	}
+
+	// finish an upload interrupted by an earlier apply, and link the uploaded videos
+	if err := s.ResumePartialUpload(ctx); err != nil {
+		return fmt.Errorf("unable to resume upload: %w", err)
+	}
+	if err := s.linkPendingUploads(ctx, plan, save); err != nil {
+		return err
+	}
+
+	ids := map[string]string{}
+	var count int
+	for _, planned := range plan.Operations {
+		if planned.Done {
+			ids[plannedId(planned.Id)] = planned.Result
+			continue
+		}
+		op, err := planned.clone(ids)
+		if err != nil {
+			return err
+		}
+		id, err := s.run(ctx, op)
+		if err != nil {
+			if isQuotaError(err) {
+				fmt.Printf("Stopping, out of quota after %d operations: %v\nApply the plan again after the quota resets to continue.\n", count, err)
+				return fmt.Errorf("%w: %v", ErrQuotaCheckpoint, err)
+			}
+			return fmt.Errorf("operation %v: %w", op, err)
+		}
+		ids[plannedId(planned.Id)] = id
+		planned.Done, planned.Result = true, id
+		count++
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Applied %d operations\n", count)
+	return nil
+}
+
+// linkPendingUploads looks for the videos of the video.create operations whose youtube_id cell has the
+// pending upload marker, i.e. the apply stopped during the upload. Videos found on the channel are linked
+// and their operations marked as done, so they're not uploaded again.
+func (s *Service) linkPendingUploads(ctx context.Context, plan *Plan, save func() error) error {
+	for _, planned := range plan.Operations {
+		if planned.Done || planned.Kind != OpVideoCreate {
+			continue
+		}
+		value, err := s.cellValue(planned.Cell)
+		if err != nil {
+			return err
+		}
+		if !isPendingUpload(value) {
+			continue
+		}
+		item, err := s.cellItem(planned.Cell)
+		if err != nil {
+			return err
+		}
+		uploaded, err := s.findUploadedVideo(item)
+		if err != nil {
+			return fmt.Errorf("checking recent uploads (%v): %w", item.String(), err)
+		}
+		if uploaded == nil {
+			// uploaded again when the operation is run
+			continue
+		}
+		fmt.Printf("Found uploaded video %s, linking instead of uploading again (%v)\n", uploaded.Id, item.String())
+		op := &Operation{
+			Kind:    OpVideoLink,
+			Subject: planned.Subject,
+			Target:  uploaded.Id,
+			Cell:    planned.Cell,
+		}
+		if _, err := s.run(ctx, op); err != nil {
+			return fmt.Errorf("linking video (%v): %w", item.String(), err)
+		}
+		if err := s.recordSync(uploaded.Id, planned.Synced); err != nil {
+			return fmt.Errorf("recording sync state (%v): %w", item.String(), err)
+		}
+		planned.Done, planned.Result = true, uploaded.Id
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cellItem returns the item in the row of a cell in an item sheet.
+func (s *Service) cellItem(cell *CellRef) (*Item, error) {
+	if expedition, ok := s.Expeditions[cell.Expedition]; ok && cell.Sheet == "item" {
+		for _, item := range expedition.Items {
+			if item.RowId == cell.RowId {
+				return item, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("item in row %d not found (%s)", cell.RowId, cell.Expedition)
+}
+
+// verifyPlan checks that the resources and cells the plan changes haven't changed since the plan was made.
+// Only the first operation on each resource is checked, because later ones were planned against the
+// changes of the earlier ones.
+func (s *Service) verifyPlan(plan *Plan) ([]string, error) {
+	var drifted []string
+	checked := map[string]bool{}
+	for _, op := range plan.Operations {
+		// the resource the live hash is of
+		resource := op.Target
+		if op.PlaylistItem != nil {
+			resource = op.PlaylistItem.Snippet.PlaylistId
+		}
+		key := op.Kind + ":" + resource
+		switch op.Kind {
+		case OpVideoUpdate, OpThumbnailSet:
+			key = "video:" + resource
+		case OpPlaylistUpdate, OpPlaylistDelete:
+			key = "playlist:" + resource
+		case OpPlaylistInsert, OpPlaylistMove, OpPlaylistRemove:
+			key = "items:" + resource
+		}
+		var cellKey string
+		if op.Cell != nil {
+			cellKey = fmt.Sprintf("cell:%s:%s:%d:%s", op.Cell.Expedition, op.Cell.Sheet, op.Cell.RowId, op.Cell.Column)
+		}
+		if op.Done {
+			checked[key], checked[cellKey] = true, true
+			continue
+		}
+
+		if op.Cell != nil && !checked[cellKey] {
+			checked[cellKey] = true
+			value, err := s.cellValue(op.Cell)
+			if err != nil {
+				return nil, err
+			}
+			// an upload interrupted while applying the plan leaves the pending upload marker, and is linked
+			// before the operations are run
+			interrupted := op.Kind == OpVideoCreate && isPendingUpload(value)
+			if value != op.Cell.Before && !interrupted {
+				drifted = append(drifted, fmt.Sprintf("%v: %s changed from %q to %q", op, op.Cell.Column, op.Cell.Before, value))
+			}
+		}
+
+		if op.LiveHash == "" || isPlannedId(resource) || checked[key] {
+			continue
+		}
+		checked[key] = true
+		var live string
+		switch op.Kind {
+		case OpVideoUpdate:
+			if err := s.spend("videos.list"); err != nil {
+				return nil, err
+			}
+			response, err := s.YoutubeService.Videos.List(videoPartsRead).Id(op.Target).Do()
+			if err != nil {
+				return nil, fmt.Errorf("youtube videos list call: %w", err)
+			}
+			if len(response.Items) == 0 {
+				drifted = append(drifted, fmt.Sprintf("%v: video %s not found", op, op.Target))
+				continue
+			}
+			if live, err = liveVideoHash(response.Items[0]); err != nil {
+				return nil, err
+			}
+		case OpPlaylistUpdate, OpPlaylistDelete:
+			if err := s.spend("playlists.list"); err != nil {
+				return nil, err
+			}
+			response, err := s.YoutubeService.Playlists.List([]string{"snippet"}).Id(op.Target).Do()
+			if err != nil {
+				return nil, fmt.Errorf("youtube playlists list call: %w", err)
+			}
+			if len(response.Items) == 0 {
+				drifted = append(drifted, fmt.Sprintf("%v: playlist %s not found", op, op.Target))
+				continue
+			}
+			if live, err = hashJSON(response.Items[0].Snippet); err != nil {
+				return nil, err
+			}
+		case OpPlaylistInsert, OpPlaylistMove, OpPlaylistRemove:
+			items, err := s.listPlaylistsItems(resource)
+			if err != nil {
+				return nil, err
+			}
+			if live, err = livePlaylistItemsHash(items); err != nil {
+				return nil, err
+			}
+		}
+		if live != op.LiveHash {
+			drifted = append(drifted, fmt.Sprintf("%v: %s has changed on YouTube", op, op.Target))
+		}
+	}
+	return drifted, nil
+}
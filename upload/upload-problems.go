@@ -0,0 +1,210 @@
+package upload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// Problem is a validation or template error that the people editing the sheet need to fix. Problems are
+// written to the "problems" tab, and the offending cell is given a note and highlighted.
+type Problem struct {
+	Sheet      *Sheet
+	RowId      int
+	Column     string
+	Expedition string
+	Item       string
+	Message    string
+}
+
+func (s *Service) AddProblem(sheet *Sheet, rowId int, column, item string, err error) {
+	problem := &Problem{
+		Sheet:   sheet,
+		RowId:   rowId,
+		Column:  column,
+		Item:    item,
+		Message: err.Error(),
+	}
+	if sheet != nil && sheet.Expedition != nil {
+		problem.Expedition = sheet.Expedition.Ref
+	}
+	fmt.Printf("Problem found (%v): %v\n", item, err)
+	s.Problems = append(s.Problems, problem)
+}
+
+// RemoveProblems removes problems that no longer apply, e.g. when a video is validated again.
+func (s *Service) RemoveProblems(problems []*Problem) {
+	if len(problems) == 0 {
+		return
+	}
+	remove := map[*Problem]bool{}
+	for _, problem := range problems {
+		remove[problem] = true
+	}
+	var kept []*Problem
+	for _, problem := range s.Problems {
+		if !remove[problem] {
+			kept = append(kept, problem)
+		}
+	}
+	s.Problems = kept
+}
+
+func (s *Service) AddItemProblem(item *Item, column string, err error) {
+	if column == "" {
+		column = "key"
+	}
+	s.AddProblem(item.Expedition.ItemSheet, item.RowId, column, item.String(), err)
+}
+
+func (s *Service) AddExpeditionProblem(expedition *Expedition, column string, err error) {
+	s.AddProblem(s.Sheets["expedition"], expedition.RowId, column, expedition.String(), err)
+	s.Problems[len(s.Problems)-1].Expedition = expedition.Ref
+}
+
+func (s *Service) AddPlaylistProblem(parent HasPlaylist, err error) {
+	switch parent := parent.(type) {
+	case *Expedition:
+		s.AddExpeditionProblem(parent, "ref", err)
+	case *Section:
+		s.AddProblem(parent.Expedition.Sheets["section"], parent.RowId, "ref", parent.String(), err)
+	}
+}
+
+// ColumnIndex returns the zero based index of the problem column, or zero if the column isn't found.
+func (p *Problem) ColumnIndex() int {
+	for i, header := range p.Sheet.Headers {
+		if header == p.Column {
+			return i
+		}
+	}
+	return 0
+}
+
+func (p *Problem) Link() string {
+	return fmt.Sprintf(
+		"https://docs.google.com/spreadsheets/d/%s/edit#gid=%d&range=%s",
+		p.Sheet.Spreadsheet.SpreadsheetId,
+		p.Sheet.SheetId,
+		getCellRange(p.ColumnIndex()+1, p.RowId),
+	)
+}
+
+var problemsHeaders = []string{"expedition", "item", "sheet", "column", "problem", "link", "spreadsheet_id", "sheet_id", "row", "column_index"}
+
+// problemCell identifies a cell that has been given a note by WriteProblems.
+type problemCell struct {
+	SpreadsheetId string
+	SheetId       int64
+	Row           int
+	Column        int
+}
+
+// WriteProblems rewrites the problems tab with the problems found during this run. Cells flagged by the
+// previous run are cleared first, so fixed problems disappear from the sheet.
+func (s *Service) WriteProblems() error {
+
+	if _, err := s.ensureTab("problems"); err != nil {
+		return fmt.Errorf("unable to create problems sheet: %w", err)
+	}
+
+	previous, err := s.SheetsService.Spreadsheets.Values.
+		Get(SPREADSHEET_ID, "problems!A2:J").
+		ValueRenderOption("UNFORMATTED_VALUE").
+		Do()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve values from problems sheet: %w", err)
+	}
+	clear := map[problemCell]bool{}
+	for _, row := range previous.Values {
+		if len(row) < len(problemsHeaders) {
+			continue
+		}
+		cell := problemCell{
+			SpreadsheetId: Cell{row[6]}.String(),
+			SheetId:       int64(Cell{row[7]}.Int()),
+			Row:           Cell{row[8]}.Int(),
+			Column:        Cell{row[9]}.Int(),
+		}
+		if cell.SpreadsheetId == "" || cell.Row == 0 {
+			continue
+		}
+		clear[cell] = true
+	}
+
+	notes := map[problemCell][]string{}
+	var values [][]any
+	for _, p := range s.Problems {
+		if p.Sheet == nil {
+			values = append(values, []any{p.Expedition, p.Item, "", p.Column, p.Message, "", "", "", "", ""})
+			continue
+		}
+		link := fmt.Sprintf("=HYPERLINK(%q, %q)", p.Link(), fmt.Sprintf("%s!%s", p.Sheet.Name, getCellRange(p.ColumnIndex()+1, p.RowId)))
+		cell := problemCell{
+			SpreadsheetId: p.Sheet.Spreadsheet.SpreadsheetId,
+			SheetId:       p.Sheet.SheetId,
+			Row:           p.RowId,
+			Column:        p.ColumnIndex(),
+		}
+		notes[cell] = append(notes[cell], p.Message)
+		values = append(values, []any{p.Expedition, p.Item, p.Sheet.Name, p.Column, p.Message, link, cell.SpreadsheetId, cell.SheetId, cell.Row, cell.Column})
+	}
+
+	fmt.Printf("Writing %d problems to problems sheet\n", len(s.Problems))
+
+	if err := s.writeTab("problems", problemsHeaders, values, "USER_ENTERED"); err != nil {
+		return fmt.Errorf("unable to write problems sheet data: %w", err)
+	}
+
+	// build the note requests for each spreadsheet: first clear all the old notes, then add the new ones
+	requests := map[string][]*sheets.Request{}
+	for cell := range clear {
+		if _, found := notes[cell]; found {
+			continue
+		}
+		requests[cell.SpreadsheetId] = append(requests[cell.SpreadsheetId], problemNoteRequest(cell, nil))
+	}
+	for cell, messages := range notes {
+		requests[cell.SpreadsheetId] = append(requests[cell.SpreadsheetId], problemNoteRequest(cell, messages))
+	}
+	var spreadsheetIds []string
+	for spreadsheetId := range requests {
+		spreadsheetIds = append(spreadsheetIds, spreadsheetId)
+	}
+	sort.Strings(spreadsheetIds)
+	for _, spreadsheetId := range spreadsheetIds {
+		request := &sheets.BatchUpdateSpreadsheetRequest{Requests: requests[spreadsheetId]}
+		if _, err := s.SheetsService.Spreadsheets.BatchUpdate(spreadsheetId, request).Do(); err != nil {
+			return fmt.Errorf("unable to update problem notes (%v): %w", spreadsheetId, err)
+		}
+	}
+
+	return nil
+}
+
+// problemNoteRequest sets the note and highlight on a problem cell. If messages is empty, the note and
+// highlight are removed.
+func problemNoteRequest(cell problemCell, messages []string) *sheets.Request {
+	data := &sheets.CellData{}
+	if len(messages) > 0 {
+		data.Note = strings.Join(messages, "\n\n")
+		data.UserEnteredFormat = &sheets.CellFormat{
+			BackgroundColor: &sheets.Color{Red: 1, Green: 0.8, Blue: 0.8},
+		}
+	}
+	return &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Range: &sheets.GridRange{
+				SheetId:          cell.SheetId,
+				StartRowIndex:    int64(cell.Row - 1),
+				EndRowIndex:      int64(cell.Row),
+				StartColumnIndex: int64(cell.Column),
+				EndColumnIndex:   int64(cell.Column + 1),
+			},
+			Rows:   []*sheets.RowData{{Values: []*sheets.CellData{data}}},
+			Fields: "note,userEnteredFormat.backgroundColor",
+		},
+	}
+}
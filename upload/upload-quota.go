@@ -0,0 +1,308 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// DefaultQuotaBudget is the default daily YouTube API quota. Set quota_budget in the global sheet to change
+// it, e.g. to leave some quota for YouTube Studio.
+const DefaultQuotaBudget = 10000
+
+// quotaCosts is the cost in units of each YouTube API method, from
+// https://developers.google.com/youtube/v3/determine_quota_cost
+var quotaCosts = map[string]int{
+	"captions.download":    200,
+	"captions.list":        50,
+	"channels.list":        1,
+	"playlistItems.delete": 50,
+	"playlistItems.insert": 50,
+	"playlistItems.list":   1,
+	"playlistItems.update": 50,
+	"playlists.delete":     50,
+	"playlists.insert":     50,
+	"playlists.list":       1,
+	"playlists.update":     50,
+	"thumbnails.set":       50,
+	"videos.insert":        1600,
+	"videos.list":          1,
+	"videos.update":        50,
+}
+
+// QuotaUsage is the quota used on one day, stored in quota.json. The quota resets at midnight Pacific time.
+type QuotaUsage struct {
+	Date       string           `json:"date"` // Pacific date, e.g. "2024-10-18"
+	Used       int              `json:"used"`
+	Calls      map[string]int   `json:"calls"`
+	Checkpoint *QuotaCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// QuotaCheckpoint records a run that stopped because it ran out of quota. Runs are idempotent, so the next
+// run continues from where this one stopped.
+type QuotaCheckpoint struct {
+	Time    time.Time `json:"time"`
+	Reason  string    `json:"reason"`
+	Pending []string  `json:"pending"` // videos that were still to be uploaded
+}
+
+// ErrQuotaCheckpoint is returned when a run stops because it ran out of quota, after the checkpoint is
+// saved. The run continues from where it stopped when it's run again after the quota resets.
+var ErrQuotaCheckpoint = errors.New("stopped at a checkpoint, out of quota")
+
+// QuotaError is returned when a call would take the usage over the budget, or YouTube returns a
+// quotaExceeded error.
+type QuotaError struct {
+	Method string
+	Reason string
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exhausted before %s: %s", e.Method, e.Reason)
+}
+
+// pacificDate returns the date that the YouTube quota is counted against.
+func pacificDate(t time.Time) string {
+	location, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		// no time zone database, so ignore daylight saving
+		location = time.FixedZone("PST", -8*60*60)
+	}
+	return t.In(location).Format("2006-01-02")
+}
+
+func quotaFilepath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home dir: %w", err)
+	}
+	return path.Join(home, ".config", "wildernessprime", "quota.json"), nil
+}
+
+// loadQuota reads the quota usage file if it hasn't been read yet, and resets the usage if the Pacific date
+// has changed.
+func (s *Service) loadQuota() error {
+	if s.Quota == nil {
+		filePath, err := quotaFilepath()
+		if err != nil {
+			return err
+		}
+		usage := &QuotaUsage{}
+		data, err := os.ReadFile(filePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("reading quota usage: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, usage); err != nil {
+				return fmt.Errorf("unmarshalling quota usage: %w", err)
+			}
+		}
+		s.Quota = usage
+	}
+	if today := pacificDate(time.Now()); s.Quota.Date != today {
+		s.Quota.Date = today
+		s.Quota.Used = 0
+		s.Quota.Calls = nil
+	}
+	if s.Quota.Calls == nil {
+		s.Quota.Calls = map[string]int{}
+	}
+	return nil
+}
+
+func (s *Service) saveQuota() error {
+	filePath, err := quotaFilepath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.Quota, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshalling quota usage: %w", err)
+	}
+	if err := os.MkdirAll(path.Dir(filePath), 0700); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("writing quota usage: %w", err)
+	}
+	return nil
+}
+
+// QuotaBudget returns the daily quota budget from the quota_budget global setting.
+func (s *Service) QuotaBudget() int {
+	if s.Global == nil || s.Global.QuotaBudget == 0 {
+		return DefaultQuotaBudget
+	}
+	return s.Global.QuotaBudget
+}
+
+// QuotaRemaining returns the quota left in the budget today.
+func (s *Service) QuotaRemaining() (int, error) {
+	if err := s.loadQuota(); err != nil {
+		return 0, err
+	}
+	return s.QuotaBudget() - s.Quota.Used, nil
+}
+
+// reserve returns a QuotaError if there isn't enough quota left for all the methods, e.g. an upload and its
+// thumbnail. Nothing is spent.
+func (s *Service) reserve(methods ...string) error {
+	remaining, err := s.QuotaRemaining()
+	if err != nil {
+		return err
+	}
+	var cost int
+	for _, method := range methods {
+		cost += quotaCosts[method]
+	}
+	if cost > remaining {
+		return &QuotaError{
+			Method: strings.Join(methods, ", "),
+			Reason: fmt.Sprintf("needs %d units, %d of the %d budget left today", cost, remaining, s.QuotaBudget()),
+		}
+	}
+	return nil
+}
+
+// spend counts a YouTube API call against the budget. It must be called before the call is made, and
+// returns a QuotaError if the call would take the usage over the budget.
+func (s *Service) spend(method string) error {
+	cost, ok := quotaCosts[method]
+	if !ok {
+		return fmt.Errorf("no quota cost for %s", method)
+	}
+	if err := s.reserve(method); err != nil {
+		return err
+	}
+	s.Quota.Used += cost
+	s.Quota.Calls[method]++
+	if err := s.saveQuota(); err != nil {
+		return fmt.Errorf("saving quota usage: %w", err)
+	}
+	return nil
+}
+
+// isQuotaError returns true if the error is a QuotaError, or a quotaExceeded error from YouTube.
+func isQuotaError(err error) bool {
+	var quotaError *QuotaError
+	if errors.As(err, &quotaError) {
+		return true
+	}
+	var apiError *googleapi.Error
+	if errors.As(err, &apiError) && apiError.Code == http.StatusForbidden {
+		for _, item := range apiError.Errors {
+			if item.Reason == "quotaExceeded" {
+				return true
+			}
+		}
+	}
+	// the resumable uploader doesn't use the client library, so the error is only in the message
+	return err != nil && strings.Contains(err.Error(), "quotaExceeded")
+}
+
+// stopForQuota records a checkpoint when the run stops because it ran out of quota, and returns
+// ErrQuotaCheckpoint.
+func (s *Service) stopForQuota(reason error) error {
+	if err := s.loadQuota(); err != nil {
+		return err
+	}
+	var quotaError *QuotaError
+	if !errors.As(reason, &quotaError) && s.Quota.Used < s.QuotaBudget() {
+		// YouTube ran out before the budget (e.g. calls made outside the tool), so don't try again today
+		s.Quota.Used = s.QuotaBudget()
+	}
+	checkpoint := &QuotaCheckpoint{Time: time.Now(), Reason: reason.Error()}
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		for _, item := range expedition.Items {
+			if item.Video && item.Ready && item.YoutubeVideo == nil {
+				checkpoint.Pending = append(checkpoint.Pending, item.String())
+			}
+		}
+	}
+	sort.Strings(checkpoint.Pending)
+	s.Quota.Checkpoint = checkpoint
+	if err := s.saveQuota(); err != nil {
+		return err
+	}
+	fmt.Printf("Stopping, out of quota: %v\n", reason)
+	fmt.Printf("Used %d of %d units today (Pacific date %s), %d videos still to upload. Run again after the quota resets to continue.\n", s.Quota.Used, s.QuotaBudget(), s.Quota.Date, len(checkpoint.Pending))
+	return fmt.Errorf("%w: %v", ErrQuotaCheckpoint, reason)
+}
+
+// printQuota prints today's usage, and the checkpoint from a run that stopped because it ran out of quota.
+func (s *Service) printQuota() error {
+	if err := s.loadQuota(); err != nil {
+		return err
+	}
+	if checkpoint := s.Quota.Checkpoint; checkpoint != nil {
+		fmt.Printf("Continuing from the run that ran out of quota at %s (%d videos were still to upload)\n", checkpoint.Time.Format(time.RFC3339), len(checkpoint.Pending))
+		s.Quota.Checkpoint = nil
+		if err := s.saveQuota(); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Quota: used %d of %d units today (Pacific date %s)\n", s.Quota.Used, s.QuotaBudget(), s.Quota.Date)
+	return nil
+}
+
+// scheduleUploads works out how many of the videos that don't exist yet can be uploaded with the quota
+// left today, after the updates to existing videos. The rest are deferred to the next run, so uploads
+// don't use up the quota the updates need, and an upload isn't started that can't be finished.
+func (s *Service) scheduleUploads() error {
+	s.UploadSlots = -1
+	if !s.Global.Production || s.Planning != nil {
+		// a plan is applied later, when the quota left may be different
+		return nil
+	}
+	var uploads, updates int
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		for _, item := range expedition.Items {
+			if !item.Video || !item.Ready || item.Invalid() {
+				continue
+			}
+			if item.YoutubeVideo == nil {
+				uploads++
+				continue
+			}
+			// videos with an unchanged hash aren't updated
+			fields, err := apply(item)
+			if err != nil {
+				continue
+			}
+			var live VideoMeta
+			hasHash := item.YoutubeVideo.Snippet != nil && decodeMeta(item.YoutubeVideo.Snippet.Description, &live) && live.Hash != ""
+			if !hasHash || live.Hash != fields.Hash || s.Force {
+				updates++
+			}
+		}
+	}
+	if uploads == 0 {
+		return nil
+	}
+	remaining, err := s.QuotaRemaining()
+	if err != nil {
+		return err
+	}
+	uploadCost := quotaCosts["videos.insert"] + quotaCosts["thumbnails.set"]
+	updatesCost := updates * quotaCosts["videos.update"]
+	s.UploadSlots = max((remaining-updatesCost)/uploadCost, 0)
+	fmt.Printf("Uploading %d videos needs about %d units, and updating %d videos about %d, %d left today\n", uploads, uploads*uploadCost, updates, updatesCost, remaining)
+	if uploads > s.UploadSlots {
+		fmt.Printf("Not enough quota to upload all the videos, %d will be uploaded in this run and %d deferred to the next\n", s.UploadSlots, uploads-s.UploadSlots)
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package upload
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// ReconcileReport is the result of comparing the videos and playlists on the channel with the sheets.
+type ReconcileReport struct {
+	Orphans    []string // on the channel, but not referenced by any sheet row
+	Relinks    []string // sheet rows with no youtube_id / playlist_id, where a matching video or playlist exists
+	Duplicates []string // more than one video or playlist with the same metadata
+	Missing    []string // ids in the sheet that aren't on the channel
+	Unmanaged  []string // on the channel with no metadata block, e.g. uploaded by hand
+	Skipped    []string // metadata refers to an expedition that isn't processed
+}
+
+func (r *ReconcileReport) Print() {
+	for _, group := range []struct {
+		title string
+		lines []string
+	}{
+		{"Orphans", r.Orphans},
+		{"Relinks", r.Relinks},
+		{"Duplicates", r.Duplicates},
+		{"Missing from channel", r.Missing},
+		{"Unmanaged", r.Unmanaged},
+		{"Skipped", r.Skipped},
+	} {
+		if len(group.lines) == 0 {
+			continue
+		}
+		sort.Strings(group.lines)
+		fmt.Printf("%s (%d):\n", group.title, len(group.lines))
+		for _, line := range group.lines {
+			fmt.Printf(" - %s\n", line)
+		}
+	}
+}
+
+// Reconcile lists the uploads and playlists on the channel, decodes the metadata block at the end of each
+// description, and matches them to the sheet rows. If write is true, missing youtube_id and playlist_id
+// cells are written back to the sheets. Only processed expeditions are checked.
+func (s *Service) Reconcile(ctx context.Context, write bool) error {
+
+	if err := s.InitialiseServiceAccount(ctx); err != nil {
+		return fmt.Errorf("init service account: %w", err)
+	}
+	if err := s.InitialiseYoutubeAuthentication(ctx); err != nil {
+		return fmt.Errorf("init youtube auth: %w", err)
+	}
+	if err := s.InitGoogleDriveService(); err != nil {
+		return fmt.Errorf("init drive service: %w", err)
+	}
+	if err := s.InitSheetsService(); err != nil {
+		return fmt.Errorf("init sheets service: %w", err)
+	}
+	if err := s.LoadSheets(); err != nil {
+		return err
+	}
+
+	report := &ReconcileReport{}
+	if err := s.reconcileVideos(report, write); err != nil {
+		return fmt.Errorf("reconciling videos: %w", err)
+	}
+	if err := s.reconcilePlaylists(report, write); err != nil {
+		return fmt.Errorf("reconciling playlists: %w", err)
+	}
+	report.Print()
+	if !write && len(report.Relinks) > 0 {
+		fmt.Println("Run with --write to write the missing ids to the sheets")
+	}
+	return nil
+}
+
+// decodeMeta decodes the metadata block at the end of a video or playlist description into v.
+func decodeMeta(description string, v any) bool {
+	matches := MetaRegex.FindStringSubmatch(description)
+	if matches == nil {
+		return false
+	}
+	b, err := base64.StdEncoding.DecodeString(matches[1])
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(b, v) == nil
+}
+
+func (s *Service) reconcileVideos(report *ReconcileReport, write bool) error {
+	videos, err := s.listChannelVideos()
+	if err != nil {
+		return err
+	}
+
+	type itemKey struct {
+		expedition, typ string
+		key             int
+	}
+	items := map[itemKey]*Item{}
+	linked := map[string]*Item{}
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		for _, item := range expedition.Items {
+			items[itemKey{expedition.Ref, item.Type, item.Key}] = item
+			if item.YoutubeId != "" {
+				linked[item.YoutubeId] = item
+			}
+		}
+	}
+
+	matched := map[*Item][]*youtube.Video{}
+	onChannel := map[string]bool{}
+	for _, video := range videos {
+		onChannel[video.Id] = true
+		var meta VideoMeta
+		if !decodeMeta(video.Snippet.Description, &meta) {
+			report.Unmanaged = append(report.Unmanaged, fmt.Sprintf("video %s %q", video.Id, video.Snippet.Title))
+			continue
+		}
+		expedition, ok := s.Expeditions[meta.Expedition]
+		if ok && !expedition.Process {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("video %s %q (%s)", video.Id, video.Snippet.Title, meta.Expedition))
+			continue
+		}
+		item, ok := items[itemKey{meta.Expedition, meta.Type, meta.Key}]
+		if !ok {
+			report.Orphans = append(report.Orphans, fmt.Sprintf("video %s %q (%s, %s, %d)", video.Id, video.Snippet.Title, meta.Expedition, meta.Type, meta.Key))
+			continue
+		}
+		matched[item] = append(matched[item], video)
+	}
+
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		for _, item := range expedition.Items {
+			if item.YoutubeId != "" && !onChannel[item.YoutubeId] {
+				report.Missing = append(report.Missing, fmt.Sprintf("video %s (%v)", item.YoutubeId, item.String()))
+			}
+			videos := matched[item]
+			if len(videos) == 0 {
+				continue
+			}
+			if len(videos) > 1 {
+				var ids []string
+				for _, video := range videos {
+					ids = append(ids, video.Id)
+				}
+				report.Duplicates = append(report.Duplicates, fmt.Sprintf("videos %s (%v)", strings.Join(ids, ", "), item.String()))
+			}
+			if item.YoutubeId != "" {
+				for _, video := range videos {
+					if video.Id != item.YoutubeId {
+						report.Orphans = append(report.Orphans, fmt.Sprintf("video %s %q (%v is linked to %s)", video.Id, video.Snippet.Title, item.String(), item.YoutubeId))
+					}
+				}
+				continue
+			}
+			if len(videos) > 1 {
+				// the duplicate must be resolved by hand
+				continue
+			}
+			video := videos[0]
+			if other, ok := linked[video.Id]; ok {
+				report.Duplicates = append(report.Duplicates, fmt.Sprintf("video %s is linked to %v but its metadata is %v", video.Id, other.String(), item.String()))
+				continue
+			}
+			report.Relinks = append(report.Relinks, fmt.Sprintf("video %s %q (%v)", video.Id, video.Snippet.Title, item.String()))
+			if write {
+				// replaces the pending upload marker, if there is one
+				if err := item.Set(s, "youtube_id", video.Id, item.PendingUpload); err != nil {
+					return fmt.Errorf("setting youtube_id (%v): %w", item.String(), err)
+				}
+				item.YoutubeId = video.Id
+				item.PendingUpload = false
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) reconcilePlaylists(report *ReconcileReport, write bool) error {
+	playlists, err := s.listChannelPlaylists()
+	if err != nil {
+		return err
+	}
+
+	// keyed by expedition and section, so the version is ignored
+	parents := map[PlaylistMeta]HasPlaylist{}
+	linked := map[string]HasPlaylist{}
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		parents[PlaylistMeta{Expedition: expedition.Ref}] = expedition
+		if expedition.PlaylistId != "" {
+			linked[expedition.PlaylistId] = expedition
+		}
+		for _, section := range expedition.Sections {
+			parents[PlaylistMeta{Expedition: expedition.Ref, Section: section.Ref}] = section
+			if section.PlaylistId != "" {
+				linked[section.PlaylistId] = section
+			}
+		}
+	}
+
+	matched := map[HasPlaylist][]*youtube.Playlist{}
+	onChannel := map[string]bool{}
+	for _, playlist := range playlists {
+		onChannel[playlist.Id] = true
+		var meta PlaylistMeta
+		if !decodeMeta(playlist.Snippet.Description, &meta) {
+			report.Unmanaged = append(report.Unmanaged, fmt.Sprintf("playlist %s %q", playlist.Id, playlist.Snippet.Title))
+			continue
+		}
+		expedition, ok := s.Expeditions[meta.Expedition]
+		if ok && !expedition.Process {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("playlist %s %q (%s)", playlist.Id, playlist.Snippet.Title, meta.Expedition))
+			continue
+		}
+		parent, ok := parents[PlaylistMeta{Expedition: meta.Expedition, Section: meta.Section}]
+		if !ok {
+			report.Orphans = append(report.Orphans, fmt.Sprintf("playlist %s %q (%s %s)", playlist.Id, playlist.Snippet.Title, meta.Expedition, meta.Section))
+			continue
+		}
+		matched[parent] = append(matched[parent], playlist)
+	}
+
+	var all []HasPlaylist
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		all = append(all, expedition)
+		for _, section := range expedition.Sections {
+			all = append(all, section)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].String() < all[j].String() })
+
+	for _, parent := range all {
+		if id := parent.GetPlaylistId(); id != "" && !onChannel[id] {
+			report.Missing = append(report.Missing, fmt.Sprintf("playlist %s (%v)", id, parent.String()))
+		}
+		playlists := matched[parent]
+		if len(playlists) == 0 {
+			continue
+		}
+		if len(playlists) > 1 {
+			var ids []string
+			for _, playlist := range playlists {
+				ids = append(ids, playlist.Id)
+			}
+			report.Duplicates = append(report.Duplicates, fmt.Sprintf("playlists %s (%v)", strings.Join(ids, ", "), parent.String()))
+		}
+		if parent.GetPlaylistId() != "" {
+			for _, playlist := range playlists {
+				if playlist.Id != parent.GetPlaylistId() {
+					report.Orphans = append(report.Orphans, fmt.Sprintf("playlist %s %q (%v is linked to %s)", playlist.Id, playlist.Snippet.Title, parent.String(), parent.GetPlaylistId()))
+				}
+			}
+			continue
+		}
+		if len(playlists) > 1 {
+			continue
+		}
+		playlist := playlists[0]
+		if other, ok := linked[playlist.Id]; ok {
+			report.Duplicates = append(report.Duplicates, fmt.Sprintf("playlist %s is linked to %v but its metadata is %v", playlist.Id, other.String(), parent.String()))
+			continue
+		}
+		report.Relinks = append(report.Relinks, fmt.Sprintf("playlist %s %q (%v)", playlist.Id, playlist.Snippet.Title, parent.String()))
+		if !write {
+			continue
+		}
+		switch parent := parent.(type) {
+		case *Expedition:
+			if err := s.Sheets["expedition"].Set(s.SheetsService, parent.RowId, "playlist_id", playlist.Id, false); err != nil {
+				return fmt.Errorf("setting playlist_id for expedition %v: %w", parent.Ref, err)
+			}
+			parent.PlaylistId = playlist.Id
+		case *Section:
+			if err := parent.Expedition.Sheets["section"].Set(s.SheetsService, parent.RowId, "playlist_id", playlist.Id, false); err != nil {
+				return fmt.Errorf("setting playlist_id for section %v: %w", parent.Ref, err)
+			}
+			parent.PlaylistId = playlist.Id
+		}
+	}
+	return nil
+}
+
+// listChannelVideos returns all the uploads on the channel, including private and scheduled videos.
+func (s *Service) listChannelVideos() ([]*youtube.Video, error) {
+	if err := s.spend("channels.list"); err != nil {
+		return nil, err
+	}
+	channels, err := s.YoutubeService.Channels.List([]string{"contentDetails"}).Mine(true).Do()
+	if err != nil {
+		return nil, fmt.Errorf("youtube channels list call: %w", err)
+	}
+	if len(channels.Items) == 0 || channels.Items[0].ContentDetails == nil || channels.Items[0].ContentDetails.RelatedPlaylists == nil {
+		return nil, fmt.Errorf("uploads playlist not found")
+	}
+	playlistItems, err := s.listPlaylistsItems(channels.Items[0].ContentDetails.RelatedPlaylists.Uploads)
+	if err != nil {
+		return nil, fmt.Errorf("listing uploads: %w", err)
+	}
+	var videoIds []string
+	for _, playlistItem := range playlistItems {
+		videoIds = append(videoIds, playlistItem.Snippet.ResourceId.VideoId)
+	}
+
+	const maxBatchSize = 50
+
+	var videos []*youtube.Video
+	for i := 0; i < len(videoIds); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(videoIds) {
+			end = len(videoIds)
+		}
+		if err := s.spend("videos.list"); err != nil {
+			return nil, err
+		}
+		response, err := s.YoutubeService.Videos.List([]string{"snippet"}).Id(videoIds[i:end]...).Do()
+		if err != nil {
+			return nil, fmt.Errorf("youtube videos list call: %w", err)
+		}
+		videos = append(videos, response.Items...)
+	}
+	return videos, nil
+}
+
+// listChannelPlaylists returns all the playlists on the channel.
+func (s *Service) listChannelPlaylists() ([]*youtube.Playlist, error) {
+	var playlists []*youtube.Playlist
+	var pageToken string
+	for {
+		if err := s.spend("playlists.list"); err != nil {
+			return nil, err
+		}
+		response, err := s.YoutubeService.Playlists.List([]string{"snippet"}).Mine(true).MaxResults(50).PageToken(pageToken).Do()
+		if err != nil {
+			return nil, fmt.Errorf("youtube playlists list call: %w", err)
+		}
+		playlists = append(playlists, response.Items...)
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+	return playlists, nil
+}
@@ -0,0 +1,243 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// Rollback restores the metadata of the videos and playlists, and the membership of the playlists, changed
+// by a run in the journal. The operations are undone in reverse order, except removed playlist items, which
+// are restored last in order of their original position. Uploads, new playlists and deleted playlists can't
+// be undone, and are reported. The rollback is written to the journal as a run of its own.
+func (s *Service) Rollback(ctx context.Context, runId string) error {
+
+	entries, err := readJournal(runId)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no changes for run %s in the journal", runId)
+	}
+
+	if err := s.InitialiseYoutubeAuthentication(ctx); err != nil {
+		return fmt.Errorf("init youtube auth: %w", err)
+	}
+
+	// playlist items inserted by the run are removed, so moving them back isn't needed
+	inserted := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Kind == OpPlaylistInsert {
+			inserted[entry.Result] = true
+		}
+	}
+
+	fmt.Printf("Rolling back %d changes from run %s\n", len(entries), runId)
+	var restored int
+	var unreverted []string
+	undo := func(entry *JournalEntry) error {
+		reason, err := s.rollbackEntry(ctx, entry)
+		if err != nil {
+			return fmt.Errorf("rolling back %s (%v): %w", entry.Kind, entry.Subject, err)
+		}
+		if reason != "" {
+			unreverted = append(unreverted, fmt.Sprintf("%s (%v): %s", entry.Kind, entry.Subject, reason))
+			return nil
+		}
+		restored++
+		return nil
+	}
+	// removed playlist items are restored last, in order of their original position, so the playlist has
+	// the items before each one when it's moved back
+	var removed []*JournalEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Kind == OpPlaylistMove && inserted[entry.Target] {
+			continue
+		}
+		if entry.Kind == OpPlaylistRemove {
+			removed = append(removed, entry)
+			continue
+		}
+		if err := undo(entry); err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(removed, func(i, j int) bool {
+		return removed[i].PlaylistItem.Snippet.Position < removed[j].PlaylistItem.Snippet.Position
+	})
+	for _, entry := range removed {
+		if err := undo(entry); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Rolled back %d changes\n", restored)
+	if len(unreverted) > 0 {
+		fmt.Printf("Unable to roll back %d changes:\n", len(unreverted))
+		for _, message := range unreverted {
+			fmt.Println(" -", message)
+		}
+	}
+	return nil
+}
+
+// rollbackEntry undoes an operation. It returns the reason if the operation can't be undone.
+func (s *Service) rollbackEntry(ctx context.Context, entry *JournalEntry) (string, error) {
+	switch entry.Kind {
+	case OpVideoUpdate:
+		if entry.Before == nil || entry.Before.Video == nil {
+			return "the video wasn't recorded before the update", nil
+		}
+		if err := s.spend("videos.list"); err != nil {
+			return "", err
+		}
+		response, err := s.YoutubeService.Videos.List(videoPartsRead).Id(entry.Target).Do()
+		if err != nil {
+			return "", fmt.Errorf("youtube videos list call: %w", err)
+		}
+		if len(response.Items) == 0 {
+			return fmt.Sprintf("video %s not found", entry.Target), nil
+		}
+		op := &Operation{
+			Kind:    OpVideoUpdate,
+			Subject: entry.Subject,
+			Target:  entry.Target,
+			Parts:   entry.Parts,
+			Video:   restoreVideo(entry.Before.Video, entry.Parts),
+			Synced:  entry.Before.Synced, // the next run compares against the sync state before the update
+			Before:  &Before{Video: response.Items[0]},
+		}
+		if _, err := s.execute(ctx, op); err != nil {
+			return "", err
+		}
+
+	case OpThumbnailSet:
+		if entry.Before == nil || len(entry.Before.Thumbnail) == 0 {
+			return "the previous thumbnail wasn't saved", nil
+		}
+		fmt.Printf("Restoring thumbnail (%v)\n", entry.Subject)
+		op := &Operation{
+			Kind:      OpThumbnailSet,
+			Subject:   entry.Subject,
+			Target:    entry.Target,
+			Thumbnail: entry.Before.Thumbnail,
+			Before:    &Before{ThumbnailURL: entry.Before.ThumbnailURL},
+		}
+		if _, err := s.execute(ctx, op); err != nil {
+			return "", err
+		}
+
+	case OpPlaylistUpdate:
+		if entry.Before == nil || entry.Before.Playlist == nil {
+			return "the playlist wasn't recorded before the update", nil
+		}
+		if err := s.spend("playlists.list"); err != nil {
+			return "", err
+		}
+		response, err := s.YoutubeService.Playlists.List([]string{"snippet"}).Id(entry.Target).Do()
+		if err != nil {
+			return "", fmt.Errorf("youtube playlists list call: %w", err)
+		}
+		if len(response.Items) == 0 {
+			return fmt.Sprintf("playlist %s not found", entry.Target), nil
+		}
+		fmt.Printf("Restoring playlist %s (%v)\n", entry.Target, entry.Subject)
+		op := &Operation{
+			Kind:     OpPlaylistUpdate,
+			Subject:  entry.Subject,
+			Target:   entry.Target,
+			Parts:    []string{"snippet"},
+			Playlist: &youtube.Playlist{Id: entry.Target, Snippet: entry.Before.Playlist.Snippet},
+			Before:   &Before{Playlist: response.Items[0]},
+		}
+		if _, err := s.execute(ctx, op); err != nil {
+			return "", err
+		}
+
+	case OpPlaylistInsert:
+		op := &Operation{
+			Kind:         OpPlaylistRemove,
+			Subject:      entry.Subject,
+			Target:       entry.Result,
+			PlaylistItem: entry.PlaylistItem,
+		}
+		if _, err := s.execute(ctx, op); err != nil {
+			return "", err
+		}
+
+	case OpPlaylistRemove:
+		// inserted again, and moved back to its position
+		snippet := entry.PlaylistItem.Snippet
+		op := &Operation{
+			Kind:         OpPlaylistInsert,
+			Subject:      entry.Subject,
+			PlaylistItem: newPlaylistItem(snippet.PlaylistId, snippet.ResourceId.VideoId),
+		}
+		id, err := s.execute(ctx, op)
+		if err != nil {
+			return "", err
+		}
+		pli := newPlaylistItem(snippet.PlaylistId, snippet.ResourceId.VideoId)
+		pli.Id = id
+		pli.Snippet.Position = snippet.Position
+		pli.Snippet.ForceSendFields = []string{"Position"}
+		op = &Operation{
+			Kind:         OpPlaylistMove,
+			Subject:      entry.Subject,
+			Target:       id,
+			PlaylistItem: pli,
+		}
+		if _, err := s.execute(ctx, op); err != nil {
+			return "", err
+		}
+
+	case OpPlaylistMove:
+		return "the position before the move wasn't recorded", nil
+
+	case OpPlaylistDelete:
+		return fmt.Sprintf("playlist %s was deleted, and a deleted playlist can't be restored", entry.Target), nil
+
+	case OpPlaylistCreate:
+		return fmt.Sprintf("playlist %s was created, delete it in YouTube Studio if it's not wanted", entry.Result), nil
+
+	case OpVideoCreate:
+		return fmt.Sprintf("video %s was uploaded, delete it in YouTube Studio if it's not wanted", entry.Result), nil
+
+	case OpVideoLink:
+		return fmt.Sprintf("the youtube_id cell was linked to video %s", entry.Target), nil
+
+	default:
+		return fmt.Sprintf("unknown operation %s", entry.Kind), nil
+	}
+	return "", nil
+}
+
+// restoreVideo returns the parts of the video as it was before the update.
+func restoreVideo(before *youtube.Video, parts []string) *youtube.Video {
+	video := &youtube.Video{Id: before.Id}
+	for _, part := range parts {
+		switch part {
+		case "snippet":
+			video.Snippet = before.Snippet
+		case "localizations":
+			video.Localizations = before.Localizations
+		case "status":
+			video.Status = before.Status
+			if video.Status != nil {
+				// false values must be sent, or YouTube resets them to the defaults
+				video.Status.ForceSendFields = []string{"SelfDeclaredMadeForKids", "Embeddable", "PublicStatsViewable", "ContainsSyntheticMedia"}
+			}
+		case "recordingDetails":
+			video.RecordingDetails = before.RecordingDetails
+		case "paidProductPlacementDetails":
+			video.PaidProductPlacementDetails = before.PaidProductPlacementDetails
+			if video.PaidProductPlacementDetails != nil {
+				video.PaidProductPlacementDetails.ForceSendFields = []string{"HasPaidProductPlacement"}
+			}
+		}
+	}
+	return video
+}
@@ -0,0 +1,354 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/v6/dropbox/files"
+)
+
+const (
+	// elevation changes smaller than this are treated as GPS noise when adding up ascent and descent
+	elevationThreshold = 5.0 // metres
+	// a track point is moving if the speed from the previous point is at least this
+	movingSpeed = 0.2 // metres per second
+	// gaps longer than this (e.g. the GPS was switched off overnight) aren't counted as moving
+	maxMovingGap = 10 * time.Minute
+)
+
+// Route is the statistics of the GPX tracks recorded for an item. Distances and elevations are in metres.
+// Templates should check the route exists with {{with .Route}}.
+type Route struct {
+	Files        []string
+	Distance     float64
+	Ascent       float64
+	Descent      float64
+	MaxElevation float64
+	MovingTime   time.Duration
+	StartTime    time.Time
+	EndTime      time.Time
+	Start, End   Point
+}
+
+// Point is a GPX track point.
+type Point struct {
+	Lat, Lon  float64
+	Elevation float64
+	Time      time.Time
+}
+
+// Km returns the distance in kilometres, rounded to one decimal place.
+func (r *Route) Km() float64 {
+	return math.Round(r.Distance/100) / 10
+}
+
+// Miles returns the distance in miles, rounded to one decimal place.
+func (r *Route) Miles() float64 {
+	return math.Round(r.Distance/1609.344*10) / 10
+}
+
+// Duration returns the time from the first point to the last point.
+func (r *Route) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// gpxTrack is a parsed GPX file.
+type gpxTrack struct {
+	Name     string
+	Segments [][]Point
+}
+
+func (t *gpxTrack) start() time.Time {
+	for _, segment := range t.Segments {
+		for _, point := range segment {
+			if !point.Time.IsZero() {
+				return point.Time
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// ImportRoutes matches the GPX files in the expedition gpx_dropbox / gpx_folder to items, and calculates
+// the route statistics. Files are matched by the gpx_filename regex template if it exists, otherwise by the
+// item date column. Several files matching one item (e.g. the GPS was restarted during the day) are joined.
+func (s *Service) ImportRoutes() error {
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		if expedition.GpxDropbox == "" && expedition.GpxFolder == "" {
+			continue
+		}
+
+		tracks, err := s.getGpxTracks(expedition)
+		if err != nil {
+			return fmt.Errorf("getting gpx files (%v): %w", expedition.Ref, err)
+		}
+		fmt.Printf("Found %d gpx files (%v)\n", len(tracks), expedition.Ref)
+
+		var filenames []string
+		for filename := range tracks {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+
+		for _, item := range expedition.Items {
+			var matched []*gpxTrack
+			if item.Templates.Lookup("gpx_filename") != nil {
+				buf := &strings.Builder{}
+				if err := item.Templates.ExecuteTemplate(buf, "gpx_filename", item); err != nil {
+					s.AddItemProblem(item, "", fmt.Errorf("execute gpx filename regex template: %w", err))
+					continue
+				}
+				if buf.Len() == 0 {
+					continue
+				}
+				filenameRegex, err := regexp.Compile(buf.String())
+				if err != nil {
+					s.AddItemProblem(item, "", fmt.Errorf("compile gpx filename regex %q: %w", buf.String(), err))
+					continue
+				}
+				for _, filename := range filenames {
+					if filenameRegex.MatchString(filename) {
+						matched = append(matched, tracks[filename])
+					}
+				}
+			} else if !item.Data["date"].Empty() {
+				y, m, d := item.Data["date"].TimeIn(item.TimeZone).Date()
+				for _, filename := range filenames {
+					start := tracks[filename].start()
+					if start.IsZero() {
+						continue
+					}
+					ty, tm, td := start.In(item.TimeZone).Date()
+					if ty == y && tm == m && td == d {
+						matched = append(matched, tracks[filename])
+					}
+				}
+			}
+			if len(matched) == 0 {
+				continue
+			}
+			item.Route = newRoute(matched)
+			if item.Setting("gpx_write").Bool() {
+				if err := s.writeRoute(item); err != nil {
+					return fmt.Errorf("writing route (%v): %w", item.String(), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// routeColumns are written to the item sheet when gpx_write is set. Columns missing from the item sheet
+// are skipped.
+var routeColumns = map[string]func(r *Route) any{
+	"route_distance":      func(r *Route) any { return math.Round(r.Distance) },
+	"route_ascent":        func(r *Route) any { return math.Round(r.Ascent) },
+	"route_descent":       func(r *Route) any { return math.Round(r.Descent) },
+	"route_max_elevation": func(r *Route) any { return math.Round(r.MaxElevation) },
+	"route_moving_time":   func(r *Route) any { return formatDuration(r.MovingTime) },
+	"route_start":         func(r *Route) any { return fmt.Sprintf("%.6f, %.6f", r.Start.Lat, r.Start.Lon) },
+	"route_end":           func(r *Route) any { return fmt.Sprintf("%.6f, %.6f", r.End.Lat, r.End.Lon) },
+}
+
+func (s *Service) writeRoute(item *Item) error {
+	headers := map[string]bool{}
+	for _, header := range item.Expedition.ItemSheet.Headers {
+		headers[header] = true
+	}
+	var columns []string
+	for column := range routeColumns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		if !headers[column] {
+			continue
+		}
+		value := routeColumns[column](item.Route)
+		if (Cell{value}).String() == item.Data[column].String() {
+			continue
+		}
+		if err := item.Set(s, column, value, true); err != nil {
+			var conflict *ConflictError
+			if errors.As(err, &conflict) {
+				s.AddItemProblem(item, column, err)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func newRoute(tracks []*gpxTrack) *Route {
+	sort.SliceStable(tracks, func(i, j int) bool { return tracks[i].start().Before(tracks[j].start()) })
+
+	route := &Route{MaxElevation: math.Inf(-1)}
+	var first = true
+	for _, track := range tracks {
+		route.Files = append(route.Files, track.Name)
+		for _, segment := range track.Segments {
+			var reference float64
+			for i, point := range segment {
+				if first {
+					route.Start = point
+					route.StartTime = point.Time
+					first = false
+				}
+				route.End = point
+				if !point.Time.IsZero() {
+					route.EndTime = point.Time
+				}
+				route.MaxElevation = math.Max(route.MaxElevation, point.Elevation)
+				if i == 0 {
+					reference = point.Elevation
+					continue
+				}
+				previous := segment[i-1]
+				distance := haversine(previous, point)
+				route.Distance += distance
+
+				// hysteresis, so noise in the elevation isn't added up
+				if climb := point.Elevation - reference; climb >= elevationThreshold {
+					route.Ascent += climb
+					reference = point.Elevation
+				} else if -climb >= elevationThreshold {
+					route.Descent -= climb
+					reference = point.Elevation
+				}
+
+				if gap := point.Time.Sub(previous.Time); gap > 0 && gap <= maxMovingGap && distance/gap.Seconds() >= movingSpeed {
+					route.MovingTime += gap
+				}
+			}
+		}
+	}
+	if math.IsInf(route.MaxElevation, -1) {
+		route.MaxElevation = 0
+	}
+	return route
+}
+
+// haversine returns the distance in metres between two points.
+func haversine(p1, p2 Point) float64 {
+	const earthRadius = 6371000.0
+	lat1, lat2 := p1.Lat*math.Pi/180, p2.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (p2.Lon - p1.Lon) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func parseGpx(name string, r io.Reader) (*gpxTrack, error) {
+	var doc struct {
+		Tracks []struct {
+			Segments []struct {
+				Points []struct {
+					Lat       float64 `xml:"lat,attr"`
+					Lon       float64 `xml:"lon,attr"`
+					Elevation float64 `xml:"ele"`
+					Time      string  `xml:"time"`
+				} `xml:"trkpt"`
+			} `xml:"trkseg"`
+		} `xml:"trk"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding gpx: %w", err)
+	}
+	track := &gpxTrack{Name: name}
+	for _, trk := range doc.Tracks {
+		for _, trkseg := range trk.Segments {
+			var segment []Point
+			for _, trkpt := range trkseg.Points {
+				point := Point{Lat: trkpt.Lat, Lon: trkpt.Lon, Elevation: trkpt.Elevation}
+				if trkpt.Time != "" {
+					t, err := time.Parse(time.RFC3339, strings.TrimSpace(trkpt.Time))
+					if err != nil {
+						return nil, fmt.Errorf("parsing point time: %w", err)
+					}
+					point.Time = t
+				}
+				segment = append(segment, point)
+			}
+			if len(segment) > 0 {
+				track.Segments = append(track.Segments, segment)
+			}
+		}
+	}
+	return track, nil
+}
+
+// getGpxTracks downloads and parses the GPX files in the expedition GPX folder.
+func (s *Service) getGpxTracks(expedition *Expedition) (map[string]*gpxTrack, error) {
+	tracks := map[string]*gpxTrack{}
+	add := func(filename string, body io.ReadCloser) error {
+		defer body.Close()
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("reading %v: %w", filename, err)
+		}
+		track, err := parseGpx(filename, bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("parsing %v: %w", filename, err)
+		}
+		tracks[filename] = track
+		return nil
+	}
+
+	switch s.StorageService {
+	case GoogleDriveStorage:
+		if expedition.GpxFolder == "" {
+			return nil, nil
+		}
+		driveFiles, err := getFilesInGoogleDriveFolder(s.DriveService, expedition.GpxFolder)
+		if err != nil {
+			return nil, err
+		}
+		for filename, file := range driveFiles {
+			if !strings.EqualFold(path.Ext(filename), ".gpx") {
+				continue
+			}
+			response, err := s.DriveService.Files.Get(file.Id).Download()
+			if err != nil {
+				return nil, fmt.Errorf("downloading drive file %v: %w", filename, err)
+			}
+			if err := add(filename, response.Body); err != nil {
+				return nil, err
+			}
+		}
+	case DropboxStorage:
+		if expedition.GpxDropbox == "" {
+			return nil, nil
+		}
+		dropboxFiles, err := getFilesInDropboxFolder(s.DropboxConfig, expedition.GpxDropbox)
+		if err != nil {
+			return nil, err
+		}
+		dbx := files.New(*s.DropboxConfig)
+		for filename, file := range dropboxFiles {
+			if !strings.EqualFold(path.Ext(filename), ".gpx") {
+				continue
+			}
+			_, download, err := dbx.Download(files.NewDownloadArg(file.Id))
+			if err != nil {
+				return nil, fmt.Errorf("downloading dropbox file %v: %w", filename, err)
+			}
+			if err := add(filename, download); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tracks, nil
+}
@@ -0,0 +1,149 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetSnapshot is the data downloaded from all the tabs of a spreadsheet. Snapshots are cached locally,
+// and reused while the Drive modified time and version of the spreadsheet are unchanged.
+type SheetSnapshot struct {
+	SpreadsheetId string              `json:"spreadsheet_id"`
+	ModifiedTime  string              `json:"modified_time"`
+	Version       int64               `json:"version"`
+	Spreadsheet   *sheets.Spreadsheet `json:"spreadsheet"`
+	Values        map[string][][]any  `json:"values"`
+}
+
+// snapshotSkip lists the tabs that are written by the tool, so are never read.
+var snapshotSkip = map[string]bool{
+	"preview_videos":    true,
+	"preview_playlists": true,
+	"preview_titles":    true,
+	"problems":          true,
+}
+
+// GetSnapshot returns the data for a spreadsheet, from the local cache if the spreadsheet hasn't changed
+// since it was cached. If the spreadsheet can't be checked (e.g. when offline), the cached snapshot is used.
+// Setting Refresh skips the cache.
+func (s *Service) GetSnapshot(spreadsheetId string) (*SheetSnapshot, error) {
+	if snapshot, ok := s.Snapshots[spreadsheetId]; ok {
+		return snapshot, nil
+	}
+
+	filePath, err := snapshotFilepath(spreadsheetId)
+	if err != nil {
+		return nil, fmt.Errorf("getting snapshot filepath: %w", err)
+	}
+
+	cached, err := readSnapshot(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading cached snapshot (%v): %w", spreadsheetId, err)
+	}
+
+	file, err := s.DriveService.Files.Get(spreadsheetId).Fields("modifiedTime, version").Do()
+	if err != nil {
+		if cached == nil || s.Refresh {
+			return nil, fmt.Errorf("unable to get spreadsheet modified time (%v): %w", spreadsheetId, err)
+		}
+		fmt.Printf("Unable to check spreadsheet %s (%v), using cached snapshot from %s\n", spreadsheetId, err, cached.ModifiedTime)
+		s.Snapshots[spreadsheetId] = cached
+		return cached, nil
+	}
+
+	if !s.Refresh && cached != nil && cached.ModifiedTime == file.ModifiedTime && cached.Version == file.Version {
+		fmt.Printf("Using cached snapshot for spreadsheet %s\n", spreadsheetId)
+		s.Snapshots[spreadsheetId] = cached
+		return cached, nil
+	}
+
+	fmt.Printf("Downloading snapshot for spreadsheet %s\n", spreadsheetId)
+
+	spreadsheet, err := s.SheetsService.Spreadsheets.Get(spreadsheetId).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve spreadsheet (%v): %w", spreadsheetId, err)
+	}
+
+	snapshot := &SheetSnapshot{
+		SpreadsheetId: spreadsheetId,
+		ModifiedTime:  file.ModifiedTime,
+		Version:       file.Version,
+		Spreadsheet:   spreadsheet,
+		Values:        map[string][][]any{},
+	}
+
+	var titles, ranges []string
+	for _, sheet := range spreadsheet.Sheets {
+		if snapshotSkip[sheet.Properties.Title] {
+			continue
+		}
+		titles = append(titles, sheet.Properties.Title)
+		ranges = append(ranges, fmt.Sprintf("'%s'", strings.ReplaceAll(sheet.Properties.Title, "'", "''")))
+	}
+	if len(ranges) > 0 {
+		response, err := s.SheetsService.Spreadsheets.Values.
+			BatchGet(spreadsheetId).
+			Ranges(ranges...).
+			ValueRenderOption("UNFORMATTED_VALUE").
+			Do()
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve values from spreadsheet (%v): %w", spreadsheetId, err)
+		}
+		if len(response.ValueRanges) != len(titles) {
+			return nil, fmt.Errorf("values response length mismatch response: %d, request: %d", len(response.ValueRanges), len(titles))
+		}
+		for i, valueRange := range response.ValueRanges {
+			snapshot.Values[titles[i]] = valueRange.Values
+		}
+	}
+
+	if err := writeSnapshot(filePath, snapshot); err != nil {
+		return nil, fmt.Errorf("writing cached snapshot (%v): %w", spreadsheetId, err)
+	}
+
+	s.Snapshots[spreadsheetId] = snapshot
+	return snapshot, nil
+}
+
+func snapshotFilepath(spreadsheetId string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home dir: %w", err)
+	}
+	return path.Join(home, ".config", "wildernessprime", "cache", spreadsheetId+".json"), nil
+}
+
+func readSnapshot(filePath string) (*SheetSnapshot, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	snapshot := &SheetSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshalling snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+func writeSnapshot(filePath string, snapshot *SheetSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshalling snapshot: %w", err)
+	}
+	if err := os.MkdirAll(path.Dir(filePath), 0700); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
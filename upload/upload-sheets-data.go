@@ -18,6 +18,7 @@ import (
 
 type Sheet struct {
 	Spreadsheet *sheets.Spreadsheet
+	SheetId     int64
 	Name        string
 	Expedition  *Expedition
 	Headers     []string
@@ -44,15 +45,31 @@ func (s *Sheet) Set(service *sheets.Service, rowId int, column string, value any
 	}
 
 	sheetRange := fmt.Sprintf("%s!%s", s.Name, cellRange)
-	if !force {
-		// Read the current value of the cell
-		resp, err := service.Spreadsheets.Values.Get(s.Spreadsheet.SpreadsheetId, sheetRange).Do()
-		if err != nil {
-			return fmt.Errorf("unable to retrieve data from sheet: %w", err)
+
+	dataId := -1
+	for i, d := range s.Data {
+		if dd, ok := d["row_id"]; ok && dd.Int() == rowId {
+			dataId = i
+			break
 		}
+	}
+	if dataId == -1 {
+		return fmt.Errorf("item with row_id %v not found in %v", rowId, s.Name)
+	}
 
+	// Read the current value of the cell
+	live, err := s.liveValue(service, sheetRange)
+	if err != nil {
+		return err
+	}
+	if force {
+		// Check the cell hasn't been edited since it was read at the start of the run
+		if live.String() != s.Data[dataId][column].String() {
+			return &ConflictError{Sheet: s.Name, Cell: cellRange, Column: column, Expected: s.Data[dataId][column].String(), Actual: live.String()}
+		}
+	} else {
 		// Check if the cell has contents
-		if len(resp.Values) > 0 && len(resp.Values[0]) > 0 && resp.Values[0][0] != "" {
+		if !live.Empty() {
 			return fmt.Errorf("cell %v is not empty", column)
 		}
 	}
@@ -63,21 +80,10 @@ func (s *Sheet) Set(service *sheets.Service, rowId int, column string, value any
 	valueRange := &sheets.ValueRange{
 		Values: [][]interface{}{{value}},
 	}
-	_, err := service.Spreadsheets.Values.Update(s.Spreadsheet.SpreadsheetId, sheetRange, valueRange).ValueInputOption("RAW").Do()
-	if err != nil {
+	if _, err := service.Spreadsheets.Values.Update(s.Spreadsheet.SpreadsheetId, sheetRange, valueRange).ValueInputOption("RAW").Do(); err != nil {
 		return fmt.Errorf("unable to update cell: %w", err)
 	}
 
-	dataId := -1
-	for i, d := range s.Data {
-		if dd, ok := d["row_id"]; ok && dd.Int() == rowId {
-			dataId = i
-			break
-		}
-	}
-	if dataId == -1 {
-		return fmt.Errorf("item with row_id %v not found in %v", rowId, s.Name)
-	}
 	s.Data[dataId][column] = Cell{value}
 	if ref, found := s.Data[dataId]["ref"]; found {
 		s.DataByRef[ref.String()][column] = Cell{value}
@@ -106,13 +112,6 @@ func (s *Sheet) Clear(service *sheets.Service, rowId int, column string) error {
 
 	sheetRange := fmt.Sprintf("%s!%s", s.Name, cellRange)
 
-	fmt.Printf("Clearing cell %v (%v) in %v\n", cellRange, column, s.Name)
-
-	_, err := service.Spreadsheets.Values.Clear(s.Spreadsheet.SpreadsheetId, sheetRange, &sheets.ClearValuesRequest{}).Do()
-	if err != nil {
-		return fmt.Errorf("unable to clear cell: %w", err)
-	}
-
 	dataId := -1
 	for i, d := range s.Data {
 		if dd, ok := d["row_id"]; ok && dd.Int() == rowId {
@@ -123,6 +122,22 @@ func (s *Sheet) Clear(service *sheets.Service, rowId int, column string) error {
 	if dataId == -1 {
 		return fmt.Errorf("item with row_id %v not found in %v", rowId, s.Name)
 	}
+
+	// Check the cell hasn't been edited since it was read at the start of the run
+	live, err := s.liveValue(service, sheetRange)
+	if err != nil {
+		return err
+	}
+	if live.String() != s.Data[dataId][column].String() {
+		return &ConflictError{Sheet: s.Name, Cell: cellRange, Column: column, Expected: s.Data[dataId][column].String(), Actual: live.String()}
+	}
+
+	fmt.Printf("Clearing cell %v (%v) in %v\n", cellRange, column, s.Name)
+
+	if _, err := service.Spreadsheets.Values.Clear(s.Spreadsheet.SpreadsheetId, sheetRange, &sheets.ClearValuesRequest{}).Do(); err != nil {
+		return fmt.Errorf("unable to clear cell: %w", err)
+	}
+
 	s.Data[dataId][column] = Cell{nil}
 	if ref, found := s.Data[dataId]["ref"]; found {
 		s.DataByRef[ref.String()][column] = Cell{nil}
@@ -131,13 +146,39 @@ func (s *Sheet) Clear(service *sheets.Service, rowId int, column string) error {
 	return nil
 }
 
+// liveValue reads the current value of a single cell from the sheet.
+func (s *Sheet) liveValue(service *sheets.Service, sheetRange string) (Cell, error) {
+	resp, err := service.Spreadsheets.Values.Get(s.Spreadsheet.SpreadsheetId, sheetRange).ValueRenderOption("UNFORMATTED_VALUE").Do()
+	if err != nil {
+		return Cell{}, fmt.Errorf("unable to retrieve data from sheet: %w", err)
+	}
+	if len(resp.Values) > 0 && len(resp.Values[0]) > 0 {
+		return Cell{resp.Values[0][0]}, nil
+	}
+	return Cell{nil}, nil
+}
+
+// ConflictError is returned when writing to a cell that has been edited in the sheet since it was read at
+// the start of the run.
+type ConflictError struct {
+	Sheet, Cell, Column string
+	Expected, Actual    string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("cell %v (%v) in %v was edited during the run: expected %q, found %q", e.Cell, e.Column, e.Sheet, e.Expected, e.Actual)
+}
+
 type Global struct {
 	Preview                  bool
 	Production               bool
 	Thumbnails               bool
 	Titles                   bool
+	TwoPass                  bool // TwoPass renders the videos again after new videos are created
+	QuotaBudget              int  // daily YouTube API quota budget, zero for the default
 	PreviewThumbnailsFolder  string
 	PreviewThumbnailsDropbox string
+	TimeZone                 *time.Location
 	Data                     map[string]Cell
 }
 
@@ -150,6 +191,8 @@ type Expedition struct {
 	ThumbnailsFolder   string
 	VideosDropbox      string
 	ThumbnailsDropbox  string
+	GpxFolder          string
+	GpxDropbox         string
 	ExpeditionPlaylist bool
 	SectionPlaylists   bool
 	DataSheetId        string
@@ -160,9 +203,13 @@ type Expedition struct {
 	Sections           []*Section
 	Items              []*Item
 	Templates          *template.Template
+	TemplateLevels     map[string]string
+	TemplateOverrides  []*TemplateOverride
 	PlaylistId         string
 	Playlist           *youtube.Playlist
 	ItemSheet          *Sheet
+	TimeZone           *time.Location
+	Global             *Global
 }
 
 func (e *Expedition) HasThumbnails() bool {
@@ -205,22 +252,34 @@ type Item struct {
 	VideoDropbox         *files.FileMetadata
 	ThumbnailDropbox     *files.FileMetadata
 	YoutubeId            string
+	PendingUpload        bool // the youtube_id cell has the pending upload marker
 	YoutubeVideo         *youtube.Video
 	YoutubeTranscript    string
 	Tags                 []string
+	TimeZone             *time.Location
+	Templates            *template.Template
+	Validation           []ValidationIssue
+	ValidationProblems   []*Problem // added for the errors in Validation, replaced when the video is validated again
+	Chapters             []*Chapter
+	Route                *Route        // from the GPX files, nil if no files match the item
+	Duration             time.Duration // from the YouTube contentDetails, zero until the video is uploaded
 }
 
 type Location struct {
-	Name      string
-	Elevation int
+	Name           string
+	Elevation      int
+	Lat, Lon       float64
+	HasCoordinates bool // Lat and Lon are set
 }
 
-func (item *Item) Metadata() (string, error) {
+// Metadata returns the encoded metadata block for the end of the video description.
+func (item *Item) Metadata(hash string) (string, error) {
 	metaData := VideoMeta{
-		Version:    1,
+		Version:    2,
 		Expedition: item.Expedition.Ref,
 		Type:       item.Type,
 		Key:        item.Key,
+		Hash:       hash,
 	}
 	metaDataBytes, err := json.Marshal(metaData)
 	if err != nil {
@@ -229,6 +288,27 @@ func (item *Item) Metadata() (string, error) {
 	return base64.StdEncoding.EncodeToString(metaDataBytes), nil
 }
 
+// Setting returns the value of a setting for the item. Settings in the item sheet override those in the
+// expedition sheet, which override the default in the global sheet.
+func (item *Item) Setting(name string) Cell {
+	if !item.Data[name].Empty() {
+		return item.Data[name]
+	}
+	return item.Expedition.Setting(name)
+}
+
+// Setting returns the value of a setting for the expedition. Settings in the expedition sheet override
+// the default in the global sheet.
+func (e *Expedition) Setting(name string) Cell {
+	if !e.Data[name].Empty() {
+		return e.Data[name]
+	}
+	if e.Global == nil {
+		return Cell{nil}
+	}
+	return e.Global.Data[name]
+}
+
 func (item *Item) String() string {
 	if item.Section != nil {
 		return fmt.Sprintf("%s, %s, %s, %d", item.Expedition.Ref, item.Type, item.Section.Ref, item.Key)
@@ -236,10 +316,36 @@ func (item *Item) String() string {
 	return fmt.Sprintf("%s, %s, %d", item.Expedition.Ref, item.Type, item.Key)
 }
 
-type Cell struct{ Value any }
+// Cell is the value of a cell in a sheet, held as a list of one. A cell resolved from a *_ref column with
+// several refs holds one linked row per ref, so templates can range over it with {{range .Data.gear}}.
+type Cell []any
+
+// Value returns the value of the cell. A list of linked rows is returned as the list.
+func (c Cell) Value() any {
+	switch len(c) {
+	case 0:
+		return nil
+	case 1:
+		return c[0]
+	}
+	return []any(c)
+}
+
+// rows returns the linked rows for a cell resolved from a *_ref column, or nil for other cells.
+func (c Cell) rows() []map[string]Cell {
+	var rows []map[string]Cell
+	for _, v := range c {
+		row, ok := v.(map[string]Cell)
+		if !ok {
+			return nil
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
 
 func (c Cell) String() string {
-	switch v := c.Value.(type) {
+	switch v := c.Value().(type) {
 	case string:
 		return v
 	case float64:
@@ -259,18 +365,132 @@ func (c Cell) String() string {
 	}
 }
 
+// Data returns the linked row for a cell resolved from a *_ref column. Columns that are themselves linked
+// are returned as nested rows, so templates can use {{.Data.camp.Data.region.name}}. A cell with several refs
+// returns an error, because it has no single row; range over it instead.
+func (c Cell) Data() (map[string]any, error) {
+	rows := c.rows()
+	switch len(rows) {
+	case 0:
+		return nil, nil
+	case 1:
+		return linkedRow(rows[0]), nil
+	}
+	return nil, fmt.Errorf("%d linked rows, use range to list them", len(rows))
+}
+
+// List returns the linked rows for a cell resolved from a *_ref column. A cell with a single ref is returned
+// as a list of one row, so {{range .Data.gear.List}} works however many refs the cell has.
+func (c Cell) List() []map[string]any {
+	var rows []map[string]any
+	for _, row := range c.rows() {
+		rows = append(rows, linkedRow(row))
+	}
+	return rows
+}
+
+func linkedRow(row map[string]Cell) map[string]any {
+	out := make(map[string]any, len(row))
+	for name, cell := range row {
+		switch rows := cell.rows(); len(rows) {
+		case 0:
+			out[name] = cell
+		case 1:
+			out[name] = linkedRow(rows[0])
+		default:
+			out[name] = cell.List()
+		}
+	}
+	return out
+}
+
 func (c Cell) Time() time.Time {
+	return c.TimeIn(time.UTC)
+}
+
+// TimeIn converts the Sheets serial date to a time, treating the wall clock time typed into the sheet as
+// local time in loc.
+func (c Cell) TimeIn(loc *time.Location) time.Time {
 	if c.Float() == 0 {
 		return time.Time{}
 	}
 	// Google Sheets base date is December 30, 1899
 	baseDate := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
-	// Add the number of days (including fractional days) to the base date
-	return baseDate.Add(time.Duration(c.Float() * 24 * float64(time.Hour)))
+	// Add the number of days (including fractional days) to the base date. Round to the nearest second
+	// because the float loses precision.
+	t := baseDate.Add(time.Duration(c.Float() * 24 * float64(time.Hour))).Round(time.Second)
+	return wallClockIn(t, loc)
+}
+
+// Duration converts a cell to a duration. Sheets durations are fractions of a day, and strings are read
+// as "h:mm:ss", "m:ss" or seconds. Sheets reads a typed "1:30" as 1 hour 30 minutes, so columns of minutes
+// and seconds must be plain text.
+func (c Cell) Duration() (time.Duration, error) {
+	switch v := c.Value().(type) {
+	case float64:
+		return time.Duration(v * 24 * float64(time.Hour)).Round(time.Second), nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case string:
+		parts := strings.Split(strings.TrimSpace(v), ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		var seconds float64
+		for _, part := range parts {
+			f, err := strconv.ParseFloat(part, 64)
+			if err != nil || f < 0 {
+				return 0, fmt.Errorf("invalid duration %q", v)
+			}
+			seconds = seconds*60 + f
+		}
+		return time.Duration(seconds * float64(time.Second)).Round(time.Second), nil
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("invalid duration %v", v)
+	}
+}
+
+// wallClockIn returns the time in loc with the same wall clock as t (which must be in UTC). During a DST
+// transition some wall clock times happen twice (e.g. 01:30 when the clocks go back), in which case the
+// earlier time is used. Other wall clock times never happen (e.g. 02:30 when the clocks go forward), in
+// which case the time is moved forward by the length of the gap (so 02:30 becomes 03:30).
+func wallClockIn(t time.Time, loc *time.Location) time.Time {
+	if loc == nil || loc == time.UTC {
+		return t
+	}
+	// offsets either side of the time are enough to cover any transition
+	_, offsetBefore := t.Add(-12 * time.Hour).In(loc).Zone()
+	_, offsetAfter := t.Add(12 * time.Hour).In(loc).Zone()
+	var found []time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		candidate := t.Add(-time.Duration(offset) * time.Second).In(loc)
+		if sameWallClock(candidate, t) {
+			found = append(found, candidate)
+		}
+	}
+	switch {
+	case len(found) == 2 && found[1].Before(found[0]):
+		return found[1]
+	case len(found) > 0:
+		return found[0]
+	default:
+		// in the gap: using the offset from before the transition moves the time forward by the gap
+		return t.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	}
+}
+
+func sameWallClock(t1, t2 time.Time) bool {
+	y1, m1, d1 := t1.Date()
+	y2, m2, d2 := t2.Date()
+	h1, min1, s1 := t1.Clock()
+	h2, min2, s2 := t2.Clock()
+	return y1 == y2 && m1 == m2 && d1 == d2 && h1 == h2 && min1 == min2 && s1 == s2
 }
 
 func (c Cell) Float() float64 {
-	switch v := c.Value.(type) {
+	switch v := c.Value().(type) {
 	case string:
 		f, err := strconv.ParseFloat(v, 64)
 		if err != nil {
@@ -295,7 +515,7 @@ func (c Cell) Float() float64 {
 }
 
 func (c Cell) Int() int {
-	switch v := c.Value.(type) {
+	switch v := c.Value().(type) {
 	case string:
 		f, err := strconv.ParseFloat(v, 64)
 		if err != nil {
@@ -320,7 +540,7 @@ func (c Cell) Int() int {
 }
 
 func (c Cell) Bool() bool {
-	switch v := c.Value.(type) {
+	switch v := c.Value().(type) {
 	case string:
 		return strings.ToLower(v) == "true"
 	case float64:
@@ -337,7 +557,7 @@ func (c Cell) Bool() bool {
 }
 
 func (c Cell) Empty() bool {
-	switch v := c.Value.(type) {
+	switch v := c.Value().(type) {
 	case string:
 		return v == ""
 	case float64:
@@ -354,7 +574,7 @@ func (c Cell) Empty() bool {
 }
 
 func (c Cell) Nil() bool {
-	switch c.Value.(type) {
+	switch c.Value().(type) {
 	case string:
 		return false
 	case float64:
@@ -371,7 +591,7 @@ func (c Cell) Nil() bool {
 }
 
 func (c Cell) Zero() bool {
-	switch v := c.Value.(type) {
+	switch v := c.Value().(type) {
 	case string:
 		return v == ""
 	case float64:
@@ -0,0 +1,160 @@
+package upload
+
+import (
+	"testing"
+	"time"
+)
+
+// serial returns the Sheets serial date for a wall clock time, as read with UNFORMATTED_VALUE.
+func serial(year int, month time.Month, day, hour, min int) float64 {
+	t := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	return t.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestCellTimeIn(t *testing.T) {
+	tests := []struct {
+		name string
+		cell Cell
+		zone string
+		want time.Time
+	}{
+		{"utc", Cell{serial(2024, 3, 10, 12, 0)}, "UTC", time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)},
+		{"kathmandu +0545", Cell{serial(2024, 5, 1, 9, 0)}, "Asia/Kathmandu", time.Date(2024, 5, 1, 3, 15, 0, 0, time.UTC)},
+		{"spring forward gap", Cell{serial(2024, 3, 10, 2, 30)}, "America/New_York", time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC)},
+		{"fall back overlap", Cell{serial(2024, 11, 3, 1, 30)}, "America/New_York", time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC)},
+		{"int serial", Cell{45658}, "UTC", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"empty", Cell{nil}, "Asia/Kathmandu", time.Time{}},
+		{"missing", Cell{}, "UTC", time.Time{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.cell.TimeIn(mustLoadLocation(t, test.zone))
+			if !got.Equal(test.want) {
+				t.Errorf("got %v, want %v", got.UTC(), test.want)
+			}
+		})
+	}
+}
+
+func TestWallClockIn(t *testing.T) {
+	tests := []struct {
+		name      string
+		wallClock time.Time
+		zone      string
+		want      time.Time
+		wantClock string
+	}{
+		{"utc", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC), "UTC", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC), "08:00 UTC"},
+		{"kathmandu +0545", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC), "Asia/Kathmandu", time.Date(2024, 6, 1, 2, 15, 0, 0, time.UTC), "08:00 +0545"},
+		{"new york summer", time.Date(2024, 7, 4, 12, 0, 0, 0, time.UTC), "America/New_York", time.Date(2024, 7, 4, 16, 0, 0, 0, time.UTC), "12:00 EDT"},
+		// 02:30 doesn't exist, so it's moved forward by the hour skipped
+		{"spring forward gap", time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC), "America/New_York", time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC), "03:30 EDT"},
+		{"london spring forward gap", time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), "Europe/London", time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), "02:30 BST"},
+		// 01:30 happens twice, and the earlier one is used
+		{"fall back overlap", time.Date(2024, 11, 3, 1, 30, 0, 0, time.UTC), "America/New_York", time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), "01:30 EDT"},
+		{"london fall back overlap", time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), "Europe/London", time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), "01:30 BST"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := wallClockIn(test.wallClock, mustLoadLocation(t, test.zone))
+			if !got.Equal(test.want) {
+				t.Errorf("got %v, want %v", got.UTC(), test.want)
+			}
+			if clock := got.Format("15:04 MST"); clock != test.wantClock {
+				t.Errorf("got wall clock %s, want %s", clock, test.wantClock)
+			}
+		})
+	}
+}
+
+func TestWallClockInNilLocation(t *testing.T) {
+	wallClock := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	if got := wallClockIn(wallClock, nil); !got.Equal(wallClock) {
+		t.Errorf("got %v, want %v", got, wallClock)
+	}
+}
+
+func TestTimeZonePrecedence(t *testing.T) {
+	tests := []struct {
+		name           string
+		globalZone     string
+		expeditionZone string
+		itemZone       string
+		want           string
+		wantProblems   int
+	}{
+		{"none", "", "", "", "UTC", 0},
+		{"global", "Europe/London", "", "", "Europe/London", 0},
+		{"expedition overrides global", "Europe/London", "Asia/Kathmandu", "", "Asia/Kathmandu", 0},
+		{"item overrides expedition", "Europe/London", "Asia/Kathmandu", "America/New_York", "America/New_York", 0},
+		{"item overrides global", "Europe/London", "", "America/New_York", "America/New_York", 0},
+		{"invalid item zone falls back to expedition", "Europe/London", "Asia/Kathmandu", "Nowhere/Atlantis", "Asia/Kathmandu", 1},
+		{"invalid expedition zone falls back to global", "Europe/London", "Nowhere/Atlantis", "", "Europe/London", 1},
+		{"invalid global zone falls back to utc", "Nowhere/Atlantis", "", "", "UTC", 1},
+		{"expedition overrides invalid global zone", "Nowhere/Atlantis", "Asia/Kathmandu", "", "Asia/Kathmandu", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := New("channel")
+			s.Sheets["global"] = &Sheet{
+				Name: "global",
+				DataByRef: map[string]map[string]Cell{
+					"time_zone": {"row_id": {3}, "value": {test.globalZone}},
+				},
+			}
+			s.Sheets["expedition"] = &Sheet{
+				Name: "expedition",
+				Data: []map[string]Cell{{
+					"row_id":     {2},
+					"ref":        {"kanch"},
+					"process":    {true},
+					"data_sheet": {"https://docs.google.com/spreadsheets/d/sheet_id/edit"},
+					"time_zone":  {test.expeditionZone},
+				}},
+			}
+			if err := s.ParseGlobal(); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.ParseExpeditions(); err != nil {
+				t.Fatal(err)
+			}
+			expedition := s.Expeditions["kanch"]
+			expedition.ItemSheet = &Sheet{
+				Name:       "item",
+				Expedition: expedition,
+				Data: []map[string]Cell{{
+					"row_id":    {2},
+					"type":      {"day"},
+					"key":       {1},
+					"time_zone": {test.itemZone},
+					"release":   {serial(2024, 6, 1, 9, 0)},
+				}},
+			}
+			if err := s.ParseItems(); err != nil {
+				t.Fatal(err)
+			}
+
+			item := expedition.Items[0]
+			if got := item.TimeZone.String(); got != test.want {
+				t.Errorf("got time zone %s, want %s", got, test.want)
+			}
+			// the release is typed as a wall clock time in the item's time zone
+			want := time.Date(2024, 6, 1, 9, 0, 0, 0, mustLoadLocation(t, test.want))
+			if !item.Release.Equal(want) {
+				t.Errorf("got release %v, want %v", item.Release, want)
+			}
+			if len(s.Problems) != test.wantProblems {
+				t.Errorf("got %d problems, want %d", len(s.Problems), test.wantProblems)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package upload
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseLinkedData resolves the *_ref columns in the expedition sheets. The linked row is stored in the
+// data under the name of the linked sheet (e.g. camp_ref is resolved to Data["camp"]). Cells containing
+// several refs (separated by newlines or commas) are resolved to a list of rows. Linked rows have their
+// own *_ref columns resolved too, so templates can use {{.Data.camp.Data.region.name}} and
+// {{range .Data.gear}}{{.name}}{{end}}.
+func (s *Service) ParseLinkedData() error {
+	reported := map[string]bool{}
+	for _, expedition := range s.Expeditions {
+		l := &linker{
+			s:          s,
+			expedition: expedition,
+			resolved:   map[*Sheet]map[string]map[string]Cell{},
+			visiting:   map[*Sheet]map[string]bool{},
+			reported:   reported,
+		}
+		for _, sheet := range expedition.Sheets {
+			for _, data := range sheet.Data {
+				ref := data["ref"].String()
+				if ref == "" {
+					l.resolveRow(sheet, data, nil)
+					continue
+				}
+				// mark the row as visiting so cycles back to it are found
+				l.visit(sheet, ref, true)
+				l.resolveRow(sheet, data, []string{sheet.Name + ":" + ref})
+				l.visit(sheet, ref, false)
+			}
+		}
+	}
+	return nil
+}
+
+// linker resolves linked data for the sheets of one expedition. Linked rows are copied before they are
+// resolved, because a row in a global sheet may link to different rows for each expedition.
+type linker struct {
+	s          *Service
+	expedition *Expedition
+	resolved   map[*Sheet]map[string]map[string]Cell
+	visiting   map[*Sheet]map[string]bool
+	reported   map[string]bool
+}
+
+func (l *linker) visit(sheet *Sheet, ref string, visiting bool) {
+	if l.visiting[sheet] == nil {
+		l.visiting[sheet] = map[string]bool{}
+	}
+	l.visiting[sheet][ref] = visiting
+}
+
+// problem adds a problem for the referring cell, unless it has already been reported.
+func (l *linker) problem(sheet *Sheet, data map[string]Cell, header string, err error) {
+	key := fmt.Sprintf("%s/%s/%d/%s/%s", sheet.Spreadsheet.SpreadsheetId, sheet.Name, data["row_id"].Int(), header, err)
+	if l.reported[key] {
+		return
+	}
+	l.reported[key] = true
+	rowId := data["row_id"].Int()
+	l.s.AddProblem(sheet, rowId, header, fmt.Sprintf("%s row %d", sheet.Name, rowId), fmt.Errorf("%s: %w", header, err))
+}
+
+// linkedSheet finds the sheet linked by a *_ref column. The expedition specific sheet is used if it
+// exists, otherwise the general sheet.
+func (l *linker) linkedSheet(name string) *Sheet {
+	if sheet, ok := l.expedition.Sheets[name]; ok {
+		return sheet
+	}
+	return l.s.Sheets[name]
+}
+
+// resolveRow resolves the *_ref columns in data, which is a row from sheet. The path is the chain of
+// rows that led here, and is used to report cycles.
+func (l *linker) resolveRow(sheet *Sheet, data map[string]Cell, path []string) {
+	for _, header := range sheet.Headers {
+		if !strings.HasSuffix(header, "_ref") {
+			continue
+		}
+		refs := splitRefs(data[header].String())
+		if len(refs) == 0 {
+			continue
+		}
+		linkedSheetName := strings.TrimSuffix(header, "_ref")
+		linkedSheet := l.linkedSheet(linkedSheetName)
+		if linkedSheet == nil {
+			key := fmt.Sprintf("%s/%s/%s", sheet.Spreadsheet.SpreadsheetId, sheet.Name, header)
+			if !l.reported[key] {
+				l.reported[key] = true
+				l.s.AddProblem(sheet, 1, header, header, fmt.Errorf("linked sheet not found: %s", linkedSheetName))
+			}
+			continue
+		}
+		var rows []map[string]Cell
+		for _, ref := range refs {
+			row, err := l.row(linkedSheet, ref, path)
+			if err != nil {
+				l.problem(sheet, data, header, err)
+				continue
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		cell := make(Cell, len(rows))
+		for i, row := range rows {
+			cell[i] = row
+		}
+		data[linkedSheetName] = cell
+	}
+}
+
+// row returns a resolved copy of the row with the ref in sheet.
+func (l *linker) row(sheet *Sheet, ref string, path []string) (map[string]Cell, error) {
+	if row, ok := l.resolved[sheet][ref]; ok {
+		return row, nil
+	}
+	path = append(path[:len(path):len(path)], sheet.Name+":"+ref)
+	if l.visiting[sheet][ref] {
+		return nil, fmt.Errorf("reference cycle: %s", strings.Join(path, " → "))
+	}
+	data := sheet.DataByRef[ref]
+	if data == nil {
+		return nil, fmt.Errorf("linked data not found in %s: %s", sheet.Name, ref)
+	}
+	row := make(map[string]Cell, len(data))
+	for k, v := range data {
+		row[k] = v
+	}
+	l.visit(sheet, ref, true)
+	l.resolveRow(sheet, row, path)
+	l.visit(sheet, ref, false)
+	if l.resolved[sheet] == nil {
+		l.resolved[sheet] = map[string]map[string]Cell{}
+	}
+	l.resolved[sheet][ref] = row
+	return row, nil
+}
+
+// splitRefs splits a *_ref cell into refs, separated by newlines or commas.
+func splitRefs(value string) []string {
+	var refs []string
+	for _, ref := range strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == ',' }) {
+		ref = strings.TrimSpace(ref)
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
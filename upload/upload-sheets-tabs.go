@@ -0,0 +1,149 @@
+package upload
+
+import (
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// ensureTab returns the properties of a tab in the main spreadsheet, creating the tab if it doesn't
+// already exist.
+func (s *Service) ensureTab(title string) (*sheets.SheetProperties, error) {
+	for _, sheet := range s.Spreadsheet.Sheets {
+		if sheet.Properties.Title == title {
+			return sheet.Properties, nil
+		}
+	}
+	fmt.Printf("Creating %s sheet\n", title)
+	request := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			AddSheet: &sheets.AddSheetRequest{
+				Properties: &sheets.SheetProperties{Title: title},
+			},
+		}},
+	}
+	response, err := s.SheetsService.Spreadsheets.BatchUpdate(SPREADSHEET_ID, request).Do()
+	if err != nil {
+		return nil, fmt.Errorf("adding sheet (%v): %w", title, err)
+	}
+	for _, reply := range response.Replies {
+		if reply.AddSheet != nil {
+			s.Spreadsheet.Sheets = append(s.Spreadsheet.Sheets, &sheets.Sheet{Properties: reply.AddSheet.Properties})
+			return reply.AddSheet.Properties, nil
+		}
+	}
+	return nil, fmt.Errorf("adding sheet (%v): no reply", title)
+}
+
+// clearTab removes everything except the headers from a tab in the main spreadsheet.
+func (s *Service) clearTab(title string, headers []string) error {
+	return s.writeTab(title, headers, nil, "RAW")
+}
+
+// writeTab replaces the contents of a tab in the main spreadsheet. The tab is created if it doesn't exist,
+// the headers in the first row are written if they don't match, and the grid is resized to fit the values
+// exactly, so no stale rows are left behind.
+func (s *Service) writeTab(title string, headers []string, values [][]any, valueInputOption string) error {
+	properties, err := s.ensureTab(title)
+	if err != nil {
+		return err
+	}
+
+	// check the headers
+	headerRange := fmt.Sprintf("%s!1:1", title)
+	existing, err := s.SheetsService.Spreadsheets.Values.Get(SPREADSHEET_ID, headerRange).Do()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve headers from %v sheet: %w", title, err)
+	}
+	var headersMatch bool
+	if len(existing.Values) > 0 && len(existing.Values[0]) >= len(headers) {
+		headersMatch = true
+		for i, header := range headers {
+			if (Cell{existing.Values[0][i]}).String() != header {
+				headersMatch = false
+				break
+			}
+		}
+	}
+
+	// resize the grid: one row for the headers and one for each value (a tab must keep at least one row
+	// below a frozen header row)
+	rowCount := int64(len(values) + 1)
+	if rowCount < 2 {
+		rowCount = 2
+	}
+	columnCount := int64(len(headers))
+	for _, row := range values {
+		if int64(len(row)) > columnCount {
+			columnCount = int64(len(row))
+		}
+	}
+	grid := &sheets.GridProperties{RowCount: rowCount}
+	fields := "gridProperties.rowCount"
+	if properties.GridProperties != nil && properties.GridProperties.ColumnCount < columnCount {
+		grid.ColumnCount = columnCount
+		fields += ",gridProperties.columnCount"
+	}
+	request := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+				Properties: &sheets.SheetProperties{
+					SheetId:        properties.SheetId,
+					GridProperties: grid,
+				},
+				Fields: fields,
+			},
+		}},
+	}
+	if _, err := s.SheetsService.Spreadsheets.BatchUpdate(SPREADSHEET_ID, request).Do(); err != nil {
+		return fmt.Errorf("unable to resize %v sheet: %w", title, err)
+	}
+	if properties.GridProperties == nil {
+		properties.GridProperties = &sheets.GridProperties{}
+	}
+	properties.GridProperties.RowCount = rowCount
+	if grid.ColumnCount > 0 {
+		properties.GridProperties.ColumnCount = grid.ColumnCount
+	}
+
+	// clear everything below the headers
+	if _, err := s.SheetsService.Spreadsheets.Values.Clear(
+		SPREADSHEET_ID,
+		fmt.Sprintf("%s!2:%d", title, rowCount),
+		&sheets.ClearValuesRequest{},
+	).Do(); err != nil {
+		return fmt.Errorf("unable to clear %v sheet data: %w", title, err)
+	}
+
+	// write the headers and values
+	var data []*sheets.ValueRange
+	if !headersMatch {
+		fmt.Printf("Writing headers to %s sheet\n", title)
+		var headerValues []any
+		for _, header := range headers {
+			headerValues = append(headerValues, header)
+		}
+		data = append(data, &sheets.ValueRange{
+			Range:  headerRange,
+			Values: [][]any{headerValues},
+		})
+	}
+	if len(values) > 0 {
+		data = append(data, &sheets.ValueRange{
+			Range:  fmt.Sprintf("%s!A2", title),
+			Values: values,
+		})
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	update := &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: valueInputOption,
+		Data:             data,
+	}
+	if _, err := s.SheetsService.Spreadsheets.Values.BatchUpdate(SPREADSHEET_ID, update).Do(); err != nil {
+		return fmt.Errorf("unable to write %v sheet data: %w", title, err)
+	}
+
+	return nil
+}
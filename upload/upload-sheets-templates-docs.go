@@ -0,0 +1,113 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// FuncDoc documents a template function. The example is executed by PrintFuncs against funcExampleData,
+// and must render the expected output.
+type FuncDoc struct {
+	Name        string
+	Usage       string
+	Description string
+	Example     string
+	Expected    string
+}
+
+var FuncDocs = []FuncDoc{
+	{"upper", "upper STRING", "Upper case.", `{{upper "Ghunsa"}}`, "GHUNSA"},
+	{"lower", "lower STRING", "Lower case.", `{{lower "Ghunsa"}}`, "ghunsa"},
+	{"commas", "commas INT", "Formats an integer with thousands separators.", `{{commas 5143}}`, "5,143"},
+	{"add", "add INT INT", "Adds two integers.", `{{add 1 2}}`, "3"},
+	{"sub", "sub INT INT", "Subtracts two integers.", `{{sub 5 2}}`, "3"},
+	{"date", "date TIME", "Formats a date as month and ordinal day.", `{{date .Release}}`, "October 21st"},
+	{"dict", "dict KEY VALUE ...", "Builds a map, e.g. to pass several values to a template.", `{{with dict "name" "Ramche"}}{{.name}}{{end}}`, "Ramche"},
+	{"nilval", "nilval", "Returns nil.", `{{if not nilval}}nil{{end}}`, "nil"},
+	{"metres", "metres NUMBER", "Formats an elevation in metres.", `{{metres .Elevation}}`, "5,143 m"},
+	{"feet", "feet NUMBER", "Converts an elevation in metres to feet.", `{{feet .Elevation}}`, "16,873 ft"},
+	{"truncate", "truncate LENGTH STRING", "Shortens a string to a number of characters at a word boundary, ending with an ellipsis.", `{{truncate 16 "Over the beautiful Sele La"}}`, "Over the…"},
+	{"join", "join SEPARATOR LIST", "Joins a list of strings, numbers or locations.", `{{join ", " .Via}}`, "Sele La, Sinion La"},
+	{"split", "split SEPARATOR STRING", "Splits a string, trimming the parts and dropping empty ones.", `{{range split "," "tent, stove,,mat"}}[{{.}}]{{end}}`, "[tent][stove][mat]"},
+	{"trim", "trim STRING", "Removes leading and trailing white space.", `{{trim "  Ramche  "}}`, "Ramche"},
+	{"pluralize", "pluralize COUNT SINGULAR [PLURAL]", "Formats a count with a noun. The plural defaults to the singular plus \"s\".", `{{pluralize 1 "pass"}}, {{pluralize 3 "pass" "passes"}}`, "1 pass, 3 passes"},
+	{"time", "time LAYOUT ZONE TIME", "Formats a time with a Go layout in a time zone. An empty zone keeps the time's own zone.", `{{time "Mon 2 Jan 15:04 MST" "Asia/Kathmandu" .Release}}`, "Mon 21 Oct 12:15 +0545"},
+	{"duration", "duration SECONDS|DURATION", "Formats a number of seconds or a duration string.", `{{duration 5400}}, {{duration "75s"}}`, "1h 30m, 1m 15s"},
+	{"default", "default DEFAULT VALUE", "Returns the value, or the default if the value is empty.", `{{"" | default "no notes"}}`, "no notes"},
+	{"seq", "seq [START] END", "Returns the integers from 1 (or START) to END.", `{{range seq 3}}{{.}}{{end}}`, "123"},
+	{"addf", "addf NUMBER NUMBER", "Adds two numbers.", `{{addf 1.5 2}}`, "3.5"},
+	{"subf", "subf NUMBER NUMBER", "Subtracts two numbers.", `{{subf 5 1.5}}`, "3.5"},
+	{"mulf", "mulf NUMBER NUMBER", "Multiplies two numbers.", `{{mulf 1.5 3}}`, "4.5"},
+	{"divf", "divf NUMBER NUMBER", "Divides two numbers.", `{{divf 7 2}}`, "3.5"},
+	{"round", "round PLACES NUMBER", "Rounds a number to a number of decimal places.", `{{round 1 (divf 22 7)}}`, "3.1"},
+	{"osmLink", "osmLink LOCATION", "Links to a location with coordinates on OpenStreetMap.", `{{osmLink (index .Via 0)}}`, "https://www.openstreetmap.org/?mlat=27.65610&mlon=87.94540#map=14/27.65610/87.94540"},
+	{"mapsLink", "mapsLink LOCATION", "Links to a location with coordinates on Google Maps.", `{{mapsLink (index .Via 0)}}`, "https://www.google.com/maps/search/?api=1&query=27.65610,87.94540"},
+	{"distance", "distance LOCATION LOCATION", "The distance in metres between two locations or route points.", `{{distance (index .Via 0) (index .Via 1) | metres}}`, "9,407 m"},
+	{"hashtag", "hashtag STRING", "Turns a string into a YouTube hashtag.", `{{hashtag "Great Himalaya Trail"}}`, "#GreatHimalayaTrail"},
+}
+
+// funcExampleData is the data used by the FuncDocs examples.
+var funcExampleData = map[string]any{
+	"Release":   time.Date(2024, 10, 21, 6, 30, 0, 0, time.UTC),
+	"Elevation": 5143,
+	"Via": []Location{
+		{Name: "Sele La", Elevation: 4290, Lat: 27.6561, Lon: 87.9454, HasCoordinates: true},
+		{Name: "Sinion La", Elevation: 4440, Lat: 27.6167, Lon: 88.0299, HasCoordinates: true},
+	},
+}
+
+// PrintFuncs lists the template functions with their examples. Each example is executed, and an error is
+// returned if any output doesn't match what's documented.
+func PrintFuncs(w io.Writer) error {
+	documented := map[string]bool{}
+	var failed []string
+	for _, doc := range FuncDocs {
+		documented[doc.Name] = true
+		output, err := executeFuncExample(doc.Example)
+		fmt.Fprintf(w, "%s\n    %s\n    %s\n    %s => %s\n", doc.Name, doc.Usage, doc.Description, doc.Example, output)
+		if err != nil {
+			fmt.Fprintf(w, "    ERROR: %v\n", err)
+			failed = append(failed, doc.Name)
+		} else if output != doc.Expected {
+			fmt.Fprintf(w, "    ERROR: expected %s\n", doc.Expected)
+			failed = append(failed, doc.Name)
+		}
+		fmt.Fprintln(w)
+	}
+
+	for _, doc := range LinkFuncDocs {
+		fmt.Fprintf(w, "%s\n    %s\n    %s\n    %s\n\n", doc.Name, doc.Usage, doc.Description, doc.Example)
+	}
+
+	var undocumented []string
+	for name := range Funcs {
+		if !documented[name] {
+			undocumented = append(undocumented, name)
+		}
+	}
+	sort.Strings(undocumented)
+
+	if len(undocumented) > 0 {
+		return fmt.Errorf("undocumented functions: %s", strings.Join(undocumented, ", "))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("examples failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func executeFuncExample(example string) (string, error) {
+	t, err := template.New("").Funcs(Funcs).Parse(example)
+	if err != nil {
+		return "", err
+	}
+	buf := &strings.Builder{}
+	if err := t.Execute(buf, funcExampleData); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
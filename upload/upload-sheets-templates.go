@@ -8,6 +8,7 @@ import (
 	"strings"
 	"text/template"
 	"time"
+	"unicode"
 )
 
 var Funcs = template.FuncMap{
@@ -15,36 +16,7 @@ var Funcs = template.FuncMap{
 	"upper": func(s string) string { return strings.ToUpper(s) },
 	"lower": func(s string) string { return strings.ToLower(s) },
 
-	"commas": func(v int) string {
-		sign := ""
-
-		// Min int64 can't be negated to a usable value, so it has to be special cased.
-		if v == math.MinInt64 {
-			return "-9,223,372,036,854,775,808"
-		}
-
-		if v < 0 {
-			sign = "-"
-			v = 0 - v
-		}
-
-		parts := []string{"", "", "", "", "", "", ""}
-		j := len(parts) - 1
-
-		for v > 999 {
-			parts[j] = strconv.FormatInt(int64(v%1000), 10)
-			switch len(parts[j]) {
-			case 2:
-				parts[j] = "0" + parts[j]
-			case 1:
-				parts[j] = "00" + parts[j]
-			}
-			v = v / 1000
-			j--
-		}
-		parts[j] = strconv.Itoa(int(v))
-		return sign + strings.Join(parts[j:], ",")
-	},
+	"commas": commas,
 
 	"add": func(a, b int) int { return a + b },
 	"sub": func(a, b int) int { return a - b },
@@ -86,4 +58,320 @@ var Funcs = template.FuncMap{
 	},
 
 	"nilval": func() any { return nil },
+
+	// metres formats an elevation in metres as "5,143 m"
+	"metres": func(v any) (string, error) {
+		f, err := toFloat(v)
+		if err != nil {
+			return "", err
+		}
+		return commas(int(math.Round(f))) + " m", nil
+	},
+
+	// feet converts an elevation in metres to feet, formatted as "16,873 ft"
+	"feet": func(v any) (string, error) {
+		f, err := toFloat(v)
+		if err != nil {
+			return "", err
+		}
+		return commas(int(math.Round(f/0.3048))) + " ft", nil
+	},
+
+	// truncate shortens a string to n characters at a word boundary, ending with an ellipsis
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if len(runes) <= n {
+			return s
+		}
+		if n < 1 {
+			return ""
+		}
+		cut := string(runes[:n-1])
+		if !unicode.IsSpace(runes[n-1]) {
+			if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+				cut = cut[:i]
+			}
+		}
+		return strings.TrimRightFunc(cut, unicode.IsSpace) + "…"
+	},
+
+	"join": func(sep string, list any) (string, error) {
+		items, err := toStrings(list)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(items, sep), nil
+	},
+
+	// split splits a string and trims the parts, dropping empty ones
+	"split": func(sep, s string) []string {
+		var out []string
+		for _, part := range strings.Split(s, sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		return out
+	},
+
+	"trim": func(s string) string { return strings.TrimSpace(s) },
+
+	// pluralize formats a count with the singular or plural noun. The plural defaults to the singular plus "s".
+	"pluralize": func(count any, singular string, plural ...string) (string, error) {
+		f, err := toFloat(count)
+		if err != nil {
+			return "", err
+		}
+		noun := singular
+		if f != 1 {
+			if len(plural) > 0 {
+				noun = plural[0]
+			} else {
+				noun = singular + "s"
+			}
+		}
+		return fmt.Sprintf("%v %s", f, noun), nil
+	},
+
+	// time formats a time with a Go layout in a time zone. An empty zone keeps the time's own zone.
+	"time": func(layout, zone string, t time.Time) (string, error) {
+		if zone != "" {
+			loc, err := time.LoadLocation(zone)
+			if err != nil {
+				return "", err
+			}
+			t = t.In(loc)
+		}
+		return t.Format(layout), nil
+	},
+
+	// duration formats a number of seconds, a time.Duration or a duration string such as "90m" as "1h 30m"
+	"duration": func(v any) (string, error) {
+		var d time.Duration
+		switch v := v.(type) {
+		case time.Duration:
+			d = v
+		case string:
+			var err error
+			if d, err = time.ParseDuration(v); err != nil {
+				return "", err
+			}
+		default:
+			f, err := toFloat(v)
+			if err != nil {
+				return "", err
+			}
+			d = time.Duration(f * float64(time.Second))
+		}
+		return formatDuration(d), nil
+	},
+
+	// default returns the value, or def if the value is empty
+	"default": func(def, v any) any {
+		if isEmpty(v) {
+			return def
+		}
+		return v
+	},
+
+	// seq returns the integers from 1 to n, or from start to end
+	"seq": func(args ...int) ([]int, error) {
+		var start, end int
+		switch len(args) {
+		case 1:
+			start, end = 1, args[0]
+		case 2:
+			start, end = args[0], args[1]
+		default:
+			return nil, errors.New("seq needs one or two arguments")
+		}
+		var out []int
+		for i := start; i <= end; i++ {
+			out = append(out, i)
+		}
+		return out, nil
+	},
+
+	"addf": func(a, b any) (float64, error) { return floatOp(a, b, func(a, b float64) float64 { return a + b }) },
+	"subf": func(a, b any) (float64, error) { return floatOp(a, b, func(a, b float64) float64 { return a - b }) },
+	"mulf": func(a, b any) (float64, error) { return floatOp(a, b, func(a, b float64) float64 { return a * b }) },
+	"divf": func(a, b any) (float64, error) {
+		if f, err := toFloat(b); err == nil && f == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return floatOp(a, b, func(a, b float64) float64 { return a / b })
+	},
+
+	// round rounds a number to a number of decimal places
+	"round": func(places int, v any) (float64, error) {
+		f, err := toFloat(v)
+		if err != nil {
+			return 0, err
+		}
+		pow := math.Pow(10, float64(places))
+		return math.Round(f*pow) / pow, nil
+	},
+
+	"osmLink":  osmLink,
+	"mapsLink": mapsLink,
+	"distance": distance,
+
+	// hashtag turns a string into a YouTube hashtag: "Great Himalaya Trail" becomes "#GreatHimalayaTrail".
+	// Apostrophes are dropped so "Nepal's" stays one word, and combining marks are kept so scripts such as
+	// Devanagari aren't broken up.
+	"hashtag": func(s string) string {
+		s = strings.NewReplacer("'", "", "’", "").Replace(s)
+		var b strings.Builder
+		for _, word := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r) }) {
+			runes := []rune(word)
+			b.WriteString(strings.ToUpper(string(runes[0])) + string(runes[1:]))
+		}
+		if b.Len() == 0 {
+			return ""
+		}
+		return "#" + b.String()
+	},
+}
+
+// toFloat converts a template value to a float64. Cells, numbers and numeric strings are accepted.
+func toFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case Cell:
+		return v.Float(), nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("%v (%T) is not a number", v, v)
+	}
+}
+
+func floatOp(a, b any, op func(a, b float64) float64) (float64, error) {
+	fa, err := toFloat(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := toFloat(b)
+	if err != nil {
+		return 0, err
+	}
+	return op(fa, fb), nil
+}
+
+// toStrings converts a template list to a slice of strings.
+func toStrings(list any) ([]string, error) {
+	switch list := list.(type) {
+	case []string:
+		return list, nil
+	case []any:
+		out := make([]string, len(list))
+		for i, v := range list {
+			out[i] = fmt.Sprint(v)
+		}
+		return out, nil
+	case []int:
+		out := make([]string, len(list))
+		for i, v := range list {
+			out[i] = strconv.Itoa(v)
+		}
+		return out, nil
+	case []Location:
+		out := make([]string, len(list))
+		for i, v := range list {
+			out[i] = v.Name
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%T is not a list", list)
+	}
+}
+
+// isEmpty returns true for nil, empty strings and lists, zero numbers, false, zero times and empty cells.
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case Cell:
+		return v.Empty()
+	case string:
+		return strings.TrimSpace(v) == ""
+	case bool:
+		return !v
+	case int:
+		return v == 0
+	case float64:
+		return v == 0
+	case time.Time:
+		return v.IsZero()
+	case []string:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// formatDuration formats a duration as "2h 5m", "5m 10s" or "45s".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+	hours := int(d / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	case minutes > 0 && seconds > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+func commas(v int) string {
+	sign := ""
+
+	// Min int64 can't be negated to a usable value, so it has to be special cased.
+	if v == math.MinInt64 {
+		return "-9,223,372,036,854,775,808"
+	}
+
+	if v < 0 {
+		sign = "-"
+		v = 0 - v
+	}
+
+	parts := []string{"", "", "", "", "", "", ""}
+	j := len(parts) - 1
+
+	for v > 999 {
+		parts[j] = strconv.FormatInt(int64(v%1000), 10)
+		switch len(parts[j]) {
+		case 2:
+			parts[j] = "0" + parts[j]
+		case 1:
+			parts[j] = "00" + parts[j]
+		}
+		v = v / 1000
+		j--
+	}
+	parts[j] = strconv.Itoa(int(v))
+	return sign + strings.Join(parts[j:], ",")
 }
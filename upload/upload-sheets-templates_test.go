@@ -0,0 +1,116 @@
+package upload
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		data    any
+		want    string
+		wantErr string
+	}{
+		// truncate
+		{"truncate short string", `{{truncate 20 "Base camp"}}`, nil, "Base camp", ""},
+		{"truncate at word boundary", `{{truncate 12 "Walking to base camp"}}`, nil, "Walking to…", ""},
+		{"truncate at space", `{{truncate 8 "Walking to base camp"}}`, nil, "Walking…", ""},
+		{"truncate shorter than first word", `{{truncate 4 "Kanchenjunga base camp"}}`, nil, "Kan…", ""},
+		{"truncate one", `{{truncate 1 "Kanchenjunga"}}`, nil, "…", ""},
+		{"truncate zero", `{{truncate 0 "Kanchenjunga"}}`, nil, "", ""},
+		{"truncate multi-byte", `{{truncate 9 "Çok güzel dağlar"}}`, nil, "Çok…", ""},
+		{"truncate multi-byte no spaces", `{{truncate 4 "日本語のテキスト"}}`, nil, "日本語…", ""},
+		{"truncate multi-byte fits", `{{truncate 5 "Lhotsé"}}`, nil, "Lhot…", ""},
+		{"truncate multi-byte exact", `{{truncate 6 "Lhotsé"}}`, nil, "Lhotsé", ""},
+
+		// pluralize
+		{"pluralize zero", `{{pluralize 0 "camp"}}`, nil, "0 camps", ""},
+		{"pluralize one", `{{pluralize 1 "camp"}}`, nil, "1 camp", ""},
+		{"pluralize many", `{{pluralize 3 "camp"}}`, nil, "3 camps", ""},
+		{"pluralize zero irregular", `{{pluralize 0 "porter" "porters"}}`, nil, "0 porters", ""},
+		{"pluralize irregular", `{{pluralize 2 "glacier" "glaciers"}}`, nil, "2 glaciers", ""},
+		{"pluralize fraction", `{{pluralize 1.5 "day"}}`, nil, "1.5 days", ""},
+		{"pluralize cell", `{{pluralize .count "pass" "passes"}}`, map[string]Cell{"count": {2.0}}, "2 passes", ""},
+		{"pluralize not a number", `{{pluralize "many" "camp"}}`, nil, "", `"many" is not a number`},
+
+		// duration
+		{"duration seconds", `{{duration 5400}}`, nil, "1h 30m", ""},
+		{"duration string", `{{duration "90m"}}`, nil, "1h 30m", ""},
+		{"duration time.Duration", `{{duration .}}`, 95 * time.Second, "1m 35s", ""},
+		{"duration zero", `{{duration 0}}`, nil, "0s", ""},
+		{"duration negative", `{{duration -60}}`, nil, "-1m", ""},
+		{"duration bad string", `{{duration "soon"}}`, nil, "", `invalid duration "soon"`},
+		{"duration clock string", `{{duration "1:30"}}`, nil, "", `unknown unit ":"`},
+		{"duration bad type", `{{duration true}}`, nil, "", "is not a number"},
+
+		// divf
+		{"divf", `{{divf 1 4}}`, nil, "0.25", ""},
+		{"divf strings", `{{divf "3" "2"}}`, nil, "1.5", ""},
+		{"divf by zero", `{{divf 1 0}}`, nil, "", "division by zero"},
+		{"divf by float zero", `{{divf 1 0.0}}`, nil, "", "division by zero"},
+		{"divf by zero string", `{{divf 1 "0"}}`, nil, "", "division by zero"},
+		{"divf by empty cell", `{{divf 1 .distance}}`, map[string]Cell{"distance": {nil}}, "", "division by zero"},
+		{"divf not a number", `{{divf 1 "x"}}`, nil, "", `"x" is not a number`},
+
+		// seq
+		{"seq end", `{{range seq 3}}{{.}}{{end}}`, nil, "123", ""},
+		{"seq start end", `{{range seq 2 4}}{{.}}{{end}}`, nil, "234", ""},
+		{"seq start equals end", `{{range seq 4 4}}{{.}}{{end}}`, nil, "4", ""},
+		{"seq start greater than end", `{{range seq 5 3}}{{.}}{{else}}empty{{end}}`, nil, "empty", ""},
+		{"seq zero", `{{range seq 0}}{{.}}{{else}}empty{{end}}`, nil, "empty", ""},
+		{"seq no arguments", `{{seq}}`, nil, "", "seq needs one or two arguments"},
+		{"seq too many arguments", `{{seq 1 2 3}}`, nil, "", "seq needs one or two arguments"},
+
+		// hashtag
+		{"hashtag", `{{hashtag "Great Himalaya Trail"}}`, nil, "#GreatHimalayaTrail", ""},
+		{"hashtag punctuation", `{{hashtag "K2: the savage mountain!"}}`, nil, "#K2TheSavageMountain", ""},
+		{"hashtag hyphen", `{{hashtag "trans-himalayan"}}`, nil, "#TransHimalayan", ""},
+		{"hashtag apostrophe", `{{hashtag "Nepal's jewel"}}`, nil, "#NepalsJewel", ""},
+		{"hashtag curly apostrophe", `{{hashtag "Nepal’s jewel"}}`, nil, "#NepalsJewel", ""},
+		{"hashtag non-ascii", `{{hashtag "über den Gipfel"}}`, nil, "#ÜberDenGipfel", ""},
+		{"hashtag accents", `{{hashtag "école de ski"}}`, nil, "#ÉcoleDeSki", ""},
+		{"hashtag devanagari", `{{hashtag "नेपाल यात्रा"}}`, nil, "#नेपालयात्रा", ""},
+		{"hashtag cjk", `{{hashtag "日本 山"}}`, nil, "#日本山", ""},
+		{"hashtag only punctuation", `{{hashtag "--!!"}}`, nil, "", ""},
+		{"hashtag empty", `{{hashtag ""}}`, nil, "", ""},
+
+		// metres and feet
+		{"metres int", `{{metres 5143}}`, nil, "5,143 m", ""},
+		{"metres float", `{{metres 5143.6}}`, nil, "5,144 m", ""},
+		{"metres float rounds down", `{{metres 8848.46}}`, nil, "8,848 m", ""},
+		{"metres string", `{{metres "5143.2"}}`, nil, "5,143 m", ""},
+		{"metres cell", `{{metres .elevation}}`, map[string]Cell{"elevation": {4130.5}}, "4,131 m", ""},
+		{"metres not a number", `{{metres "high"}}`, nil, "", `"high" is not a number`},
+		{"feet int", `{{feet 5143}}`, nil, "16,873 ft", ""},
+		{"feet float", `{{feet 8848.86}}`, nil, "29,032 ft", ""},
+		{"feet small float", `{{feet 0.5}}`, nil, "2 ft", ""},
+		{"feet cell", `{{feet .elevation}}`, map[string]Cell{"elevation": {8848.86}}, "29,032 ft", ""},
+		{"feet not a number", `{{feet "high"}}`, nil, "", `"high" is not a number`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl, err := template.New("").Funcs(Funcs).Parse(test.src)
+			if err != nil {
+				t.Fatalf("parsing %s: %v", test.src, err)
+			}
+			buf := &strings.Builder{}
+			err = tmpl.Execute(buf, test.data)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -21,11 +21,11 @@ func (s *Service) InitSheetsService() error {
 	}
 	s.SheetsService = sheetsService
 
-	spreadsheet, err := s.SheetsService.Spreadsheets.Get(SPREADSHEET_ID).Do()
+	snapshot, err := s.GetSnapshot(SPREADSHEET_ID)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve spreadsheets: %w", err)
 	}
-	s.Spreadsheet = spreadsheet
+	s.Spreadsheet = snapshot.Spreadsheet
 	return nil
 }
 
@@ -34,12 +34,7 @@ func (s *Service) ClearPreviewSheets() error {
 		fmt.Println("Clearing preview sheet")
 
 		// clear "preview_videos", "preview_titles" sheet, but leave first row (headers)
-		_, err := s.SheetsService.Spreadsheets.Values.Clear(
-			SPREADSHEET_ID,
-			fmt.Sprintf("%s!2:1000", "preview_videos"),
-			&sheets.ClearValuesRequest{},
-		).Do()
-		if err != nil {
+		if err := s.clearTab("preview_videos", previewVideosHeaders); err != nil {
 			return fmt.Errorf("unable to clear preview_videos sheet data: %w", err)
 		}
 	}
@@ -47,12 +42,7 @@ func (s *Service) ClearPreviewSheets() error {
 	if s.Global.Titles {
 		fmt.Println("Clearing preview titles sheet")
 
-		_, err := s.SheetsService.Spreadsheets.Values.Clear(
-			SPREADSHEET_ID,
-			fmt.Sprintf("%s!2:1000", "preview_titles"),
-			&sheets.ClearValuesRequest{},
-		).Do()
-		if err != nil {
+		if err := s.clearTab("preview_titles", previewTitlesHeaders); err != nil {
 			return fmt.Errorf("unable to clear preview_titles sheet data: %w", err)
 		}
 	}
@@ -72,6 +62,8 @@ func (s *Service) GetAllSheetsData() error {
 			"expedition":        true,
 			"preview_videos":    true,
 			"preview_playlists": true,
+			"preview_titles":    true,
+			"problems":          true,
 		}
 		if skip[sheetData.Properties.Title] {
 			continue
@@ -92,11 +84,11 @@ func (s *Service) GetAllSheetsData() error {
 }
 
 func (s *Service) GetSheets(expedition *Expedition) error {
-	spreadsheet, err := s.SheetsService.Spreadsheets.Get(expedition.DataSheetId).Do()
+	snapshot, err := s.GetSnapshot(expedition.DataSheetId)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve spreadsheets: %w", err)
 	}
-	expedition.Spreadsheet = spreadsheet
+	expedition.Spreadsheet = snapshot.Spreadsheet
 
 	for _, sheet := range expedition.Spreadsheet.Sheets {
 		if err := s.GetSheetData(expedition, sheet.Properties.Title); err != nil {
@@ -124,6 +116,11 @@ func (s *Service) GetSheetData(expedition *Expedition, titles ...string) error {
 			sheet.Spreadsheet = s.Spreadsheet
 			s.Sheets[sheet.Name] = sheet
 		}
+		for _, sheetData := range sheet.Spreadsheet.Sheets {
+			if sheetData.Properties.Title == title {
+				sheet.SheetId = sheetData.Properties.SheetId
+			}
+		}
 
 		if expedition != nil {
 			fmt.Printf("Getting raw data for sheet %s (%s)\n", title, expedition.Ref)
@@ -131,16 +128,17 @@ func (s *Service) GetSheetData(expedition *Expedition, titles ...string) error {
 			fmt.Printf("Getting raw data for sheet %s\n", title)
 		}
 
-		values, err := s.SheetsService.Spreadsheets.Values.
-			Get(sheet.Spreadsheet.SpreadsheetId, title).
-			ValueRenderOption("UNFORMATTED_VALUE").
-			Do()
+		snapshot, err := s.GetSnapshot(sheet.Spreadsheet.SpreadsheetId)
 		if err != nil {
 			return fmt.Errorf("unable to retrieve values from sheet (%v): %w", title, err)
 		}
+		values, ok := snapshot.Values[title]
+		if !ok {
+			return fmt.Errorf("sheet not found in spreadsheet (%v)", title)
+		}
 		hasRef := false
 		refColumn := 0
-		for i, row := range values.Values {
+		for i, row := range values {
 			if i == 0 {
 				for columnIndex, header := range row {
 					if header.(string) == "ref" {
@@ -187,6 +185,8 @@ func (s *Service) ParseGlobal() error {
 		Production:               s.Sheets["global"].DataByRef["production"]["value"].Bool(),
 		Thumbnails:               s.Sheets["global"].DataByRef["thumbnails"]["value"].Bool(),
 		Titles:                   s.Sheets["global"].DataByRef["titles"]["value"].Bool(),
+		TwoPass:                  s.Sheets["global"].DataByRef["two_pass"]["value"].Bool(),
+		QuotaBudget:              s.Sheets["global"].DataByRef["quota_budget"]["value"].Int(),
 		PreviewThumbnailsFolder:  s.Sheets["global"].DataByRef["preview_thumbnails_folder"]["value"].String(),
 		PreviewThumbnailsDropbox: s.Sheets["global"].DataByRef["preview_thumbnails_dropbox"]["value"].String(),
 		//	Data:
@@ -197,12 +197,24 @@ func (s *Service) ParseGlobal() error {
 	}
 	s.Global.Data = data
 
+	s.Global.TimeZone = time.UTC
+	if !data["time_zone"].Empty() {
+		loc, err := time.LoadLocation(data["time_zone"].String())
+		if err != nil {
+			// reported like the expedition and item time zones, and UTC is used
+			row := s.Sheets["global"].DataByRef["time_zone"]["row_id"].Int()
+			s.AddProblem(s.Sheets["global"], row, "value", "global", fmt.Errorf("unable to load time zone: %w", err))
+		} else {
+			s.Global.TimeZone = loc
+		}
+	}
+
 	return nil
 }
 
 func (item *Item) Set(s *Service, column string, value any, force bool) error {
 	if !force && !item.Data[column].Empty() {
-		return fmt.Errorf("cell %v is not empty, value = %#v (%v)", column, item.Data[column].Value, item.String())
+		return fmt.Errorf("cell %v is not empty, value = %#v (%v)", column, item.Data[column].Value(), item.String())
 	}
 	if err := item.Expedition.ItemSheet.Set(s.SheetsService, item.RowId, column, value, force); err != nil {
 		return fmt.Errorf("unable to update cell %v (%v): %w", column, item.String(), err)
@@ -238,6 +250,8 @@ func getCellRange(columnID, rowID int) string {
 	return fmt.Sprintf("%s%d", columnLetter, rowID)
 }
 
+var previewVideosHeaders = []string{"expedition", "type", "key", "video_privacy_status", "video_publish_at", "video_title", "video_description", "video_tags", "video_recording", "video_status", "video_drift", "video_validation"}
+
 func (s *Service) WriteVideosPreview() error {
 
 	if !s.Global.Preview {
@@ -246,13 +260,24 @@ func (s *Service) WriteVideosPreview() error {
 
 	// write preview data
 	// expedition	type	key	changed	video_privacy_status	video_publish_at	video_title	video_description
-	headers := []string{"video_privacy_status", "video_publish_at", "video_title", "video_description", "video_tags"}
-	var values [][]any
-
+	// followed by a column for each language
+	var languages []string
+	found := map[string]bool{}
 	var keys []*Item
-	for key := range s.VideoPreviewData {
+	for key, data := range s.VideoPreviewData {
 		keys = append(keys, key)
+		for name := range data {
+			if strings.HasPrefix(name, localizationPreviewPrefix) && !found[name] {
+				found[name] = true
+				languages = append(languages, name)
+			}
+		}
 	}
+	sort.Strings(languages)
+	allHeaders := append(append([]string{}, previewVideosHeaders...), languages...)
+	headers := allHeaders[3:]
+	var values [][]any
+
 	sort.Slice(keys, func(i, j int) bool {
 		if keys[i].Expedition != keys[j].Expedition {
 			return keys[i].Expedition.RowId < keys[j].Expedition.RowId
@@ -272,43 +297,23 @@ func (s *Service) WriteVideosPreview() error {
 		values = append(values, value)
 	}
 
-	// Define the range to append the data
-	rangeToAppend := fmt.Sprintf("%s!A1", "preview_videos")
-
-	// Create a request to append the specified values
-	valueRange := &sheets.ValueRange{
-		Values: values,
-	}
-
-	// Execute the append request
-	_, err := s.SheetsService.Spreadsheets.Values.Append(SPREADSHEET_ID, rangeToAppend, valueRange).
-		ValueInputOption("RAW").
-		InsertDataOption("INSERT_ROWS").
-		Do()
-	if err != nil {
-		return fmt.Errorf("unable to append rows to preview_videos sheet: %w", err)
+	if err := s.writeTab("preview_videos", allHeaders, values, "RAW"); err != nil {
+		return fmt.Errorf("unable to write rows to preview_videos sheet: %w", err)
 	}
 
 	return nil
 }
 
+var previewPlaylistsHeaders = []string{"expedition", "section", "playlist_title", "playlist_description", "playlist_content"}
+
 func (s *Service) WritePlaylistsPreview() error {
 
 	if !s.Global.Preview {
 		return nil
 	}
 
-	_, err := s.SheetsService.Spreadsheets.Values.Clear(
-		SPREADSHEET_ID,
-		fmt.Sprintf("%s!2:1000", "preview_playlists"),
-		&sheets.ClearValuesRequest{},
-	).Do()
-	if err != nil {
-		return fmt.Errorf("unable to clear preview_playlists sheet data: %w", err)
-	}
-
 	// write preview data
-	headers := []string{"playlist_title", "playlist_description", "playlist_content"}
+	headers := previewPlaylistsHeaders[2:]
 	var values [][]any
 
 	var keys []HasPlaylist
@@ -346,21 +351,8 @@ func (s *Service) WritePlaylistsPreview() error {
 		values = append(values, value)
 	}
 
-	// Define the range to append the data
-	rangeToAppend := fmt.Sprintf("%s!A1", "preview_playlists")
-
-	// Create a request to append the specified values
-	valueRange := &sheets.ValueRange{
-		Values: values,
-	}
-
-	// Execute the append request
-	_, err = s.SheetsService.Spreadsheets.Values.Append(SPREADSHEET_ID, rangeToAppend, valueRange).
-		ValueInputOption("RAW").
-		InsertDataOption("INSERT_ROWS").
-		Do()
-	if err != nil {
-		return fmt.Errorf("unable to append rows to preview_playlists sheet: %w", err)
+	if err := s.writeTab("preview_playlists", previewPlaylistsHeaders, values, "RAW"); err != nil {
+		return fmt.Errorf("unable to write rows to preview_playlists sheet: %w", err)
 	}
 
 	return nil
@@ -384,6 +376,8 @@ func (s *Service) ParseExpeditions() error {
 			ThumbnailsFolder:   data["thumbnails_folder"].String(),
 			VideosDropbox:      data["videos_dropbox"].String(),
 			ThumbnailsDropbox:  data["thumbnails_dropbox"].String(),
+			GpxFolder:          data["gpx_folder"].String(),
+			GpxDropbox:         data["gpx_dropbox"].String(),
 			ExpeditionPlaylist: data["expedition_playlist"].Bool(),
 			SectionPlaylists:   data["section_playlists"].Bool(),
 			DataSheetId:        sheetId,
@@ -391,7 +385,17 @@ func (s *Service) ParseExpeditions() error {
 			Data:               data,
 			Sheets:             map[string]*Sheet{},
 			SectionsByRef:      map[string]*Section{},
-			Templates:          template.New("").Funcs(Funcs),
+			Templates:          template.New("").Funcs(Funcs).Funcs(s.LinkFuncs()),
+			TimeZone:           s.Global.TimeZone,
+			Global:             s.Global,
+		}
+		if !data["time_zone"].Empty() {
+			loc, err := time.LoadLocation(data["time_zone"].String())
+			if err != nil {
+				s.AddExpeditionProblem(s.Expeditions[ref], "time_zone", fmt.Errorf("unable to load time zone: %w", err))
+			} else {
+				s.Expeditions[ref].TimeZone = loc
+			}
 		}
 	}
 	return nil
@@ -447,16 +451,30 @@ func (s *Service) ParseItems() error {
 
 		for _, data := range expedition.ItemSheet.Data {
 
+			// coordinate problems are reported once the item has been created
+			var coordinateProblems []func(item *Item)
+			reportCoordinates := func(column string, err error) {
+				coordinateProblems = append(coordinateProblems, func(item *Item) { s.AddItemProblem(item, column, err) })
+			}
 			parseLocation := func(s string) Location {
+				lat, lon, hasCoordinates := parseCoordinates(data, s, reportCoordinates)
 				if data[s+"_name"].Empty() {
+					for _, column := range []string{s + "_lat", s + "_lon"} {
+						if !data[column].Empty() {
+							reportCoordinates(column, fmt.Errorf("%s is set without %s_name, so it's ignored", column, s))
+							break
+						}
+					}
 					return Location{}
 				}
-				elevation, ok := data[s+"_elevation"]
-				if ok {
-					return Location{Name: data[s+"_name"].String(), Elevation: elevation.Int()}
-				} else {
-					return Location{Name: data[s+"_name"].String()}
+				location := Location{Name: data[s+"_name"].String()}
+				if elevation, ok := data[s+"_elevation"]; ok {
+					location.Elevation = elevation.Int()
 				}
+				if hasCoordinates {
+					location.Lat, location.Lon, location.HasCoordinates = lat, lon, true
+				}
+				return location
 			}
 			var via []Location
 			viaId := 1
@@ -472,16 +490,29 @@ func (s *Service) ParseItems() error {
 			var sectionRef string
 			if data["section_ref"].String() != "" {
 				sectionRef = data["section_ref"].String()
-				var ok bool
-				section, ok = expedition.SectionsByRef[sectionRef]
-				if !ok {
-					return fmt.Errorf("section not found (%v): %s", expedition.Ref, sectionRef)
+				section = expedition.SectionsByRef[sectionRef]
+			}
+
+			timeZone := expedition.TimeZone
+			var timeZoneErr error
+			if !data["time_zone"].Empty() {
+				loc, err := time.LoadLocation(data["time_zone"].String())
+				if err != nil {
+					timeZoneErr = fmt.Errorf("unable to load time zone: %w", err)
+				} else {
+					timeZone = loc
 				}
 			}
 
 			var release time.Time
 			if !data["release"].Empty() {
-				release = data["release"].Time()
+				release = data["release"].TimeIn(timeZone)
+			}
+
+			youtubeId := data["youtube_id"].String()
+			var pendingUpload bool
+			if isPendingUpload(youtubeId) {
+				youtubeId, pendingUpload = "", true
 			}
 
 			item := &Item{
@@ -490,7 +521,8 @@ func (s *Service) ParseItems() error {
 				Type:              data["type"].String(),
 				Key:               data["key"].Int(),
 				Video:             data["video"].Bool(),
-				YoutubeId:         data["youtube_id"].String(),
+				YoutubeId:         youtubeId,
+				PendingUpload:     pendingUpload,
 				Ready:             data["ready"].Bool(),
 				DoThumbnail:       data["do_thumbnail"].Bool(),
 				YoutubeTranscript: data["transcript"].String(),
@@ -503,80 +535,20 @@ func (s *Service) ParseItems() error {
 				Via:               via,
 				Section:           section,
 				SectionRef:        sectionRef,
+				TimeZone:          timeZone,
 			}
 			expedition.Items = append(expedition.Items, item)
 			if section != nil {
 				section.Items = append(section.Items, item)
+			} else if sectionRef != "" {
+				s.AddItemProblem(item, "section_ref", fmt.Errorf("section not found: %s", sectionRef))
 			}
-		}
-	}
-	return nil
-}
-
-func (s *Service) ParseTemplates() error {
-	for _, expedition := range s.Expeditions {
-		if !expedition.Process {
-			continue
-		}
-		sheet, ok := expedition.Sheets["template"]
-		if !ok {
-			continue
-		}
-
-		for _, data := range sheet.Data {
-			if data["template"].Empty() {
-				continue
-			}
-			ref := data["ref"].String()
-			_, err := expedition.Templates.New(ref).Parse(data["template"].String())
-			if err != nil {
-				return fmt.Errorf("error parsing template (%v): %w", ref, err)
-			}
-		}
-
-		for _, data := range s.Sheets["template"].Data {
-			if data["template"].Empty() {
-				continue
-			}
-			ref := data["ref"].String()
-			_, err := expedition.Templates.New(ref).Parse(data["template"].String())
-			if err != nil {
-				return fmt.Errorf("error parsing template (%v): %w", ref, err)
+			if timeZoneErr != nil {
+				s.AddItemProblem(item, "time_zone", timeZoneErr)
 			}
-		}
-	}
-	return nil
-}
-
-func (s *Service) ParseLinkedData() error {
-	for _, expedition := range s.Expeditions {
-		for _, sheet := range expedition.Sheets {
-			for _, header := range sheet.Headers {
-				if strings.HasSuffix(header, "_ref") {
-					linkedSheetName := strings.TrimSuffix(header, "_ref")
-
-					// first check if the expedition specific linked sheet exists
-					linkedSheet, ok := expedition.Sheets[linkedSheetName]
-					if !ok {
-						// if not, check if a general linked sheet exists
-						linkedSheet, ok = s.Sheets[linkedSheetName]
-						if !ok {
-							return fmt.Errorf("linked sheet not found: %s", linkedSheetName)
-						}
-					}
-
-					for i, data := range sheet.Data {
-						if data[header].Empty() {
-							continue
-						}
-						ref := data[header].String()
-						linkedData := linkedSheet.DataByRef[ref]
-						if linkedData == nil {
-							return fmt.Errorf("linked data not found: %s", ref)
-						}
-						sheet.Data[i][linkedSheetName] = Cell{linkedData}
-					}
-				}
+			parseCoordinates(data, "recording", reportCoordinates)
+			for _, problem := range coordinateProblems {
+				problem(item)
 			}
 		}
 	}
@@ -593,18 +565,24 @@ func (s *Service) UpdateVideoTitles() error {
 				continue
 			}
 			f := func(templateName, columnName string) error {
-				if expedition.Templates.Lookup(templateName) == nil {
+				if item.Templates.Lookup(templateName) == nil {
 					return nil
 				}
 				buf := &strings.Builder{}
-				if err := expedition.Templates.ExecuteTemplate(buf, templateName, item); err != nil {
-					return fmt.Errorf("unable to execute template (%v): %w", templateName, err)
+				if err := item.Templates.ExecuteTemplate(buf, templateName, item); err != nil {
+					s.AddItemProblem(item, columnName, fmt.Errorf("unable to execute template (%v): %w", templateName, err))
+					return nil
 				}
 				value := buf.String()
 				if item.Data[columnName].String() == value {
 					return nil
 				}
 				if err := item.Set(s, columnName, value, true); err != nil {
+					var conflict *ConflictError
+					if errors.As(err, &conflict) {
+						s.AddItemProblem(item, columnName, err)
+						return nil
+					}
 					return fmt.Errorf("unable to update column %v (%v): %w", columnName, item.String(), err)
 				}
 				return nil
@@ -0,0 +1,217 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// SyncState is the rendered metadata of each video the last time it was synced to YouTube, stored in
+// sync-state.json. It's the common ancestor in the three-way compare of synced, live and rendered
+// metadata, which tells edits made in the sheet apart from edits made in YouTube Studio.
+type SyncState struct {
+	Videos map[string]map[string]string `json:"videos"` // video id -> field -> value
+}
+
+const (
+	DriftOurs     = "ours"     // changed in the sheet since the last sync: YouTube is updated
+	DriftTheirs   = "theirs"   // changed in YouTube Studio since the last sync: the edit is kept
+	DriftConflict = "conflict" // changed in both: the edit is kept unless Force is set
+)
+
+func syncStateFilepath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home dir: %w", err)
+	}
+	return path.Join(home, ".config", "wildernessprime", "sync-state.json"), nil
+}
+
+// loadSyncState reads the sync state file, if it hasn't been read yet.
+func (s *Service) loadSyncState() error {
+	if s.SyncState != nil {
+		return nil
+	}
+	filePath, err := syncStateFilepath()
+	if err != nil {
+		return err
+	}
+	state := &SyncState{Videos: map[string]map[string]string{}}
+	data, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading sync state: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return fmt.Errorf("unmarshalling sync state: %w", err)
+		}
+		if state.Videos == nil {
+			state.Videos = map[string]map[string]string{}
+		}
+	}
+	s.SyncState = state
+	return nil
+}
+
+// recordSync stores the synced metadata of a video, and writes the sync state file.
+func (s *Service) recordSync(videoId string, synced map[string]string) error {
+	if err := s.loadSyncState(); err != nil {
+		return err
+	}
+	s.SyncState.Videos[videoId] = synced
+	filePath, err := syncStateFilepath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.SyncState, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshalling sync state: %w", err)
+	}
+	if err := os.MkdirAll(path.Dir(filePath), 0700); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+	return nil
+}
+
+// syncField is a field that's compared three ways. restore sets the rendered value to the live value.
+// normalize, if set, is applied to the synced value before it's compared, as it was to rendered and live.
+type syncField struct {
+	name, rendered, live string
+	restore              func()
+	normalize            func(string) string
+}
+
+// descriptionBody returns the description without the metadata block, which changes with the hash of
+// the other fields, so it isn't compared.
+func descriptionBody(description string) string {
+	if loc := MetaRegex.FindStringIndex(description); loc != nil {
+		description = description[:loc[0]]
+	}
+	return strings.TrimRight(description, "\n")
+}
+
+// syncFields returns the text fields that are commonly edited in YouTube Studio. Privacy, status and
+// recording details are always driven by the sheet.
+func (y *YoutubeFields) syncFields(video *youtube.Video) []syncField {
+	snippet := video.Snippet
+	if snippet == nil {
+		snippet = &youtube.VideoSnippet{}
+	}
+	sortedTags := func(tags []string) string {
+		tags = append([]string{}, tags...)
+		sort.Strings(tags)
+		return strings.Join(tags, "\n")
+	}
+	meta := strings.TrimPrefix(y.Description, descriptionBody(y.Description))
+	fields := []syncField{
+		{name: "title", rendered: y.Title, live: snippet.Title, restore: func() { y.Title = snippet.Title }},
+		{
+			name:     "description",
+			rendered: descriptionBody(y.Description),
+			live:     descriptionBody(snippet.Description),
+			// the live body is kept, with the current metadata block
+			restore:   func() { y.Description = descriptionBody(snippet.Description) + meta },
+			normalize: descriptionBody,
+		},
+		{name: "tags", rendered: sortedTags(y.Tags), live: sortedTags(snippet.Tags), restore: func() { y.Tags = snippet.Tags }},
+		{name: "category_id", rendered: y.CategoryId, live: snippet.CategoryId, restore: func() { y.CategoryId = snippet.CategoryId }},
+		{name: "default_language", rendered: y.DefaultLanguage, live: snippet.DefaultLanguage, restore: func() { y.DefaultLanguage = snippet.DefaultLanguage }},
+		{name: "default_audio_language", rendered: y.DefaultAudioLanguage, live: snippet.DefaultAudioLanguage, restore: func() { y.DefaultAudioLanguage = snippet.DefaultAudioLanguage }},
+	}
+	var langs []string
+	for lang := range y.Localizations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		var live string
+		localization, exists := video.Localizations[lang]
+		if exists {
+			live = formatLocalization(localization)
+		}
+		fields = append(fields, syncField{
+			name:     "localization." + lang,
+			rendered: formatLocalization(y.Localizations[lang]),
+			live:     live,
+			restore: func() {
+				if exists {
+					y.Localizations[lang] = localization
+				} else {
+					delete(y.Localizations, lang)
+				}
+			},
+		})
+	}
+	return fields
+}
+
+// resolveDrift compares the rendered fields with the live video and the last synced state. Fields edited
+// in YouTube Studio are restored to the live value, so they're not reverted. Fields edited in both places
+// are conflicts, and are only overwritten if Force is set. Videos with no sync state (e.g. before the
+// first sync) are updated as before. resolveDrift returns the state to record after the video is synced,
+// and the drifted fields for the preview.
+func (s *Service) resolveDrift(item *Item, fields *YoutubeFields, video *youtube.Video) (map[string]string, map[string]string, error) {
+	if err := s.loadSyncState(); err != nil {
+		return nil, nil, err
+	}
+	base, hasBase := s.SyncState.Videos[item.YoutubeId]
+	synced := map[string]string{}
+	drift := map[string]string{}
+	for _, field := range fields.syncFields(video) {
+		synced[field.name] = field.rendered
+		previous, known := base[field.name]
+		if field.normalize != nil {
+			// sync states recorded before the field was normalized have the full value
+			previous = field.normalize(previous)
+		}
+		if field.rendered == field.live || !hasBase || !known || field.live == previous {
+			continue
+		}
+		if field.rendered == previous {
+			drift[field.name] = DriftTheirs
+			field.restore()
+			continue
+		}
+		drift[field.name] = DriftConflict
+		if s.Force {
+			continue
+		}
+		field.restore()
+		// the conflict is reported again until it's resolved in the sheet or with --force
+		synced[field.name] = previous
+	}
+	return synced, drift, nil
+}
+
+// storeDriftPreview writes the drifted fields to the video_drift preview column.
+func (s *Service) storeDriftPreview(item *Item, drift map[string]string) {
+	if _, ok := s.VideoPreviewData[item]; !ok {
+		s.VideoPreviewData[item] = map[string]any{}
+	}
+	if len(drift) == 0 {
+		s.VideoPreviewData[item]["video_drift"] = "=== IN SYNC ==="
+		return
+	}
+	var lines []string
+	for name, kind := range drift {
+		switch {
+		case kind == DriftTheirs:
+			lines = append(lines, fmt.Sprintf("%s: edited in YouTube Studio, keeping the edit", name))
+		case s.Force:
+			lines = append(lines, fmt.Sprintf("%s: CONFLICT, overwriting the YouTube Studio edit", name))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: CONFLICT, edited in the sheet and YouTube Studio, keeping the edit", name))
+		}
+	}
+	sort.Strings(lines)
+	s.VideoPreviewData[item]["video_drift"] = strings.Join(lines, "\n")
+}
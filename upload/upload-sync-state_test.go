@@ -0,0 +1,99 @@
+package upload
+
+import (
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+func TestResolveDrift(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       map[string]string // nil if the video hasn't been synced
+		rendered   string
+		live       string
+		force      bool
+		wantDrift  string
+		wantTitle  string // the title sent to YouTube
+		wantSynced string // the title recorded as synced
+	}{
+		{"no base", nil, "Day 1", "Day one", false, "", "Day 1", "Day 1"},
+		{"in sync", map[string]string{"title": "Day 1"}, "Day 1", "Day 1", false, "", "Day 1", "Day 1"},
+		{"ours", map[string]string{"title": "Day 1"}, "Day 1: Ghunsa", "Day 1", false, "", "Day 1: Ghunsa", "Day 1: Ghunsa"},
+		{"theirs", map[string]string{"title": "Day 1"}, "Day 1", "Day 1 (edited)", false, DriftTheirs, "Day 1 (edited)", "Day 1"},
+		{"conflict", map[string]string{"title": "Day 1"}, "Day 1: Ghunsa", "Day 1 (edited)", false, DriftConflict, "Day 1 (edited)", "Day 1"},
+		{"conflict with force", map[string]string{"title": "Day 1"}, "Day 1: Ghunsa", "Day 1 (edited)", true, DriftConflict, "Day 1: Ghunsa", "Day 1: Ghunsa"},
+		{"conflict resolved in the sheet", map[string]string{"title": "Day 1"}, "Day 1 (edited)", "Day 1 (edited)", false, "", "Day 1 (edited)", "Day 1 (edited)"},
+		{"field not in base", map[string]string{"description": "body"}, "Day 1", "Day one", false, "", "Day 1", "Day 1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := New("channel")
+			s.Force = test.force
+			s.SyncState = &SyncState{Videos: map[string]map[string]string{}}
+			if test.base != nil {
+				s.SyncState.Videos["video_id"] = test.base
+			}
+			item := &Item{YoutubeId: "video_id"}
+			fields := &YoutubeFields{Title: test.rendered}
+			video := &youtube.Video{Id: "video_id", Snippet: &youtube.VideoSnippet{Title: test.live}}
+
+			synced, drift, err := s.resolveDrift(item, fields, video)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if drift["title"] != test.wantDrift {
+				t.Errorf("got drift %q, want %q", drift["title"], test.wantDrift)
+			}
+			if fields.Title != test.wantTitle {
+				t.Errorf("got title %q, want %q", fields.Title, test.wantTitle)
+			}
+			if synced["title"] != test.wantSynced {
+				t.Errorf("got synced title %q, want %q", synced["title"], test.wantSynced)
+			}
+		})
+	}
+}
+
+func TestResolveDriftDescription(t *testing.T) {
+	tests := []struct {
+		name            string
+		base            string
+		rendered        string
+		live            string
+		wantDrift       string
+		wantDescription string
+	}{
+		{"only the metadata block changed", "Walking to Ghunsa", "Walking to Ghunsa\n\n{new}", "Walking to Ghunsa\n\n{old}", "", "Walking to Ghunsa\n\n{new}"},
+		{"theirs keeps the current metadata block", "Walking to Ghunsa", "Walking to Ghunsa\n\n{new}", "Walking to Ghunsa (edited)\n\n{old}", DriftTheirs, "Walking to Ghunsa (edited)\n\n{new}"},
+		{"base recorded with the metadata block", "Walking to Ghunsa\n\n{old}", "Walking to Ghunsa\n\n{new}", "Walking to Ghunsa (edited)\n\n{old}", DriftTheirs, "Walking to Ghunsa (edited)\n\n{new}"},
+		{"conflict keeps the current metadata block", "Walking to Ghunsa", "Walking to Phale\n\n{new}", "Walking to Ghunsa (edited)\n\n{old}", DriftConflict, "Walking to Ghunsa (edited)\n\n{new}"},
+		{"ours", "Walking to Ghunsa", "Walking to Phale\n\n{new}", "Walking to Ghunsa\n\n{old}", "", "Walking to Phale\n\n{new}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := New("channel")
+			s.SyncState = &SyncState{Videos: map[string]map[string]string{
+				"video_id": {"description": test.base},
+			}}
+			item := &Item{YoutubeId: "video_id"}
+			fields := &YoutubeFields{Description: test.rendered}
+			video := &youtube.Video{Id: "video_id", Snippet: &youtube.VideoSnippet{Description: test.live}}
+
+			synced, drift, err := s.resolveDrift(item, fields, video)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if drift["description"] != test.wantDrift {
+				t.Errorf("got drift %q, want %q", drift["description"], test.wantDrift)
+			}
+			if fields.Description != test.wantDescription {
+				t.Errorf("got description %q, want %q", fields.Description, test.wantDescription)
+			}
+			// the synced description never includes the metadata block
+			if body := descriptionBody(synced["description"]); synced["description"] != body {
+				t.Errorf("synced description %q includes the metadata block", synced["description"])
+			}
+		})
+	}
+}
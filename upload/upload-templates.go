@@ -0,0 +1,202 @@
+package upload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Templates are defined at three levels, from lowest to highest precedence: the global template sheet,
+// the expedition template sheet, and template_<name> columns in the item sheet. A definition replaces any
+// definition of the same name from a lower level, and can call the replaced definition with
+// {{template "super" .}}.
+const (
+	GlobalTemplateLevel     = "global"
+	ExpeditionTemplateLevel = "expedition"
+	ItemTemplateLevel       = "item"
+
+	superTemplate = "super"
+
+	itemTemplatePrefix = "template_"
+)
+
+// TemplateOverride records a template definition that replaces a definition from the same or a lower level.
+type TemplateOverride struct {
+	Name   string
+	Level  string
+	Parent string
+	Item   *Item
+	Super  bool // the override calls the replaced definition
+}
+
+func (o *TemplateOverride) String() string {
+	message := fmt.Sprintf("%s template shadows %s definition", o.Level, o.Parent)
+	if !o.Super {
+		message += ` without calling {{template "super"}}`
+	}
+	return message
+}
+
+func (s *Service) ParseTemplates() error {
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+
+		expedition.TemplateLevels = map[string]string{}
+		expedition.TemplateOverrides = nil
+
+		if sheet, ok := s.Sheets["template"]; ok {
+			s.parseTemplateSheet(expedition, sheet, GlobalTemplateLevel)
+		}
+		if sheet, ok := expedition.Sheets["template"]; ok {
+			s.parseTemplateSheet(expedition, sheet, ExpeditionTemplateLevel)
+		}
+
+		for _, item := range expedition.Items {
+			item.Templates = expedition.Templates
+
+			var columns []string
+			for column, value := range item.Data {
+				if strings.HasPrefix(column, itemTemplatePrefix) && !value.Empty() {
+					columns = append(columns, column)
+				}
+			}
+			if len(columns) == 0 {
+				continue
+			}
+			sort.Strings(columns)
+
+			templates, err := expedition.Templates.Clone()
+			if err != nil {
+				return fmt.Errorf("cloning templates (%v): %w", item.String(), err)
+			}
+			item.Templates = templates
+
+			levels := map[string]string{}
+			for name, level := range expedition.TemplateLevels {
+				levels[name] = level
+			}
+			for _, column := range columns {
+				name := strings.TrimPrefix(column, itemTemplatePrefix)
+				override, err := defineTemplate(templates, levels, ItemTemplateLevel, name, item.Data[column].String())
+				if err != nil {
+					s.AddItemProblem(item, column, fmt.Errorf("error parsing template (%v): %w", name, err))
+					continue
+				}
+				if override != nil {
+					override.Item = item
+					expedition.TemplateOverrides = append(expedition.TemplateOverrides, override)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) parseTemplateSheet(expedition *Expedition, sheet *Sheet, level string) {
+	for _, data := range sheet.Data {
+		if data["template"].Empty() {
+			continue
+		}
+		ref := data["ref"].String()
+		override, err := defineTemplate(expedition.Templates, expedition.TemplateLevels, level, ref, data["template"].String())
+		if err != nil {
+			s.AddProblem(sheet, data["row_id"].Int(), "template", ref, fmt.Errorf("error parsing template (%v): %w", ref, err))
+			continue
+		}
+		if override != nil {
+			expedition.TemplateOverrides = append(expedition.TemplateOverrides, override)
+		}
+	}
+}
+
+// defineTemplate parses text as the named template in templates, and records the level it was defined at.
+// If the name is already defined, the existing definition is kept as "<level>:<name>", and calls to
+// {{template "super"}} in the new definition are pointed at it. The override is returned, or nil if
+// nothing was replaced.
+func defineTemplate(templates *template.Template, levels map[string]string, level, name, text string) (*TemplateOverride, error) {
+
+	// parse into a copy, so the existing definition is untouched if parsing fails
+	scratch, err := templates.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("cloning templates: %w", err)
+	}
+	t, err := scratch.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if t.Tree == nil {
+		return nil, fmt.Errorf("template is empty")
+	}
+
+	var supers []*parse.TemplateNode
+	walkTemplateNodes(t.Tree.Root, func(node *parse.TemplateNode) {
+		if node.Name == superTemplate {
+			supers = append(supers, node)
+		}
+	})
+
+	var override *TemplateOverride
+	parent := templates.Lookup(name)
+	if parent == nil || parent.Tree == nil {
+		if len(supers) > 0 {
+			return nil, fmt.Errorf(`template calls {{template "super"}} but there is no %s definition to call`, name)
+		}
+	} else {
+		parentLevel := levels[name]
+		parentName := fmt.Sprintf("%s:%s", parentLevel, name)
+		if _, err := templates.AddParseTree(parentName, parent.Tree); err != nil {
+			return nil, fmt.Errorf("keeping %s definition: %w", parentLevel, err)
+		}
+		for _, node := range supers {
+			node.Name = parentName
+		}
+		override = &TemplateOverride{Name: name, Level: level, Parent: parentLevel, Super: len(supers) > 0}
+	}
+
+	// copy across any templates created by {{define}} in the text
+	for _, defined := range scratch.Templates() {
+		if defined.Name() == name || defined.Tree == nil {
+			continue
+		}
+		if existing := templates.Lookup(defined.Name()); existing != nil && existing.Tree == defined.Tree {
+			continue
+		}
+		if _, err := templates.AddParseTree(defined.Name(), defined.Tree); err != nil {
+			return nil, fmt.Errorf("adding template %v: %w", defined.Name(), err)
+		}
+	}
+	if _, err := templates.AddParseTree(name, t.Tree); err != nil {
+		return nil, fmt.Errorf("adding template %v: %w", name, err)
+	}
+	levels[name] = level
+
+	return override, nil
+}
+
+// walkTemplateNodes calls fn for every {{template}} action in the parse tree.
+func walkTemplateNodes(node parse.Node, fn func(node *parse.TemplateNode)) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			walkTemplateNodes(n, fn)
+		}
+	case *parse.IfNode:
+		walkTemplateNodes(node.List, fn)
+		walkTemplateNodes(node.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateNodes(node.List, fn)
+		walkTemplateNodes(node.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateNodes(node.List, fn)
+		walkTemplateNodes(node.ElseList, fn)
+	case *parse.TemplateNode:
+		fn(node)
+	}
+}
@@ -0,0 +1,115 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// PendingUploadMarker is written to the youtube_id cell before a video is uploaded, and replaced with the id
+// when the upload finishes. If the run dies in between, the next run finds the marker and looks for the
+// video on the channel instead of uploading it again.
+const PendingUploadMarker = "pending"
+
+// recentUploadsCount is the number of recent uploads that are checked for a video matching the item before
+// a new upload is started.
+const recentUploadsCount = 50
+
+// isPendingUpload returns true if the youtube_id cell contains the pending upload marker.
+func isPendingUpload(youtubeId string) bool {
+	return strings.HasPrefix(youtubeId, PendingUploadMarker)
+}
+
+// findUploadedVideo looks for a video in the recent uploads on the channel with metadata matching the item.
+// It returns nil if there's no match.
+func (s *Service) findUploadedVideo(item *Item) (*youtube.Video, error) {
+	if s.RecentUploads == nil {
+		uploads, err := s.listRecentUploads()
+		if err != nil {
+			return nil, err
+		}
+		s.RecentUploads = uploads
+	}
+	var videoId string
+	for _, upload := range s.RecentUploads {
+		var meta VideoMeta
+		if !decodeMeta(upload.Snippet.Description, &meta) {
+			continue
+		}
+		if meta.Expedition != item.Expedition.Ref || meta.Type != item.Type || meta.Key != item.Key {
+			continue
+		}
+		if videoId != "" {
+			return nil, fmt.Errorf("found more than one upload matching the item: %s and %s", videoId, upload.Snippet.ResourceId.VideoId)
+		}
+		videoId = upload.Snippet.ResourceId.VideoId
+	}
+	if videoId == "" {
+		return nil, nil
+	}
+	if err := s.spend("videos.list"); err != nil {
+		return nil, err
+	}
+	response, err := s.YoutubeService.Videos.List(videoPartsRead).Id(videoId).Do()
+	if err != nil {
+		return nil, fmt.Errorf("youtube videos list call: %w", err)
+	}
+	if len(response.Items) == 0 {
+		return nil, nil
+	}
+	return response.Items[0], nil
+}
+
+// listRecentUploads returns the most recent uploads on the channel, including private and scheduled videos.
+func (s *Service) listRecentUploads() ([]*youtube.PlaylistItem, error) {
+	if err := s.spend("channels.list"); err != nil {
+		return nil, err
+	}
+	channels, err := s.YoutubeService.Channels.List([]string{"contentDetails"}).Mine(true).Do()
+	if err != nil {
+		return nil, fmt.Errorf("youtube channels list call: %w", err)
+	}
+	if len(channels.Items) == 0 || channels.Items[0].ContentDetails == nil || channels.Items[0].ContentDetails.RelatedPlaylists == nil {
+		return nil, fmt.Errorf("uploads playlist not found")
+	}
+	if err := s.spend("playlistItems.list"); err != nil {
+		return nil, err
+	}
+	response, err := s.YoutubeService.PlaylistItems.
+		List([]string{"snippet"}).
+		PlaylistId(channels.Items[0].ContentDetails.RelatedPlaylists.Uploads).
+		MaxResults(recentUploadsCount).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("youtube playlistItems list call: %w", err)
+	}
+	return response.Items, nil
+}
+
+// linkUploadedVideo links the item to a video that was uploaded by an earlier run, and updates it from the
+// sheet.
+func (s *Service) linkUploadedVideo(ctx context.Context, item *Item, video *youtube.Video) error {
+	fmt.Printf("Found uploaded video %s, linking instead of uploading again (%v)\n", video.Id, item.String())
+	op := &Operation{
+		Kind:    OpVideoLink,
+		Subject: item.String(),
+		Target:  video.Id,
+		Cell:    cellRef(item.Expedition.ItemSheet, item.RowId, "youtube_id"),
+	}
+	if _, err := s.execute(ctx, op); err != nil {
+		return fmt.Errorf("linking video: %w", err)
+	}
+	item.YoutubeId = video.Id
+	item.YoutubeVideo = video
+	item.PendingUpload = false
+	if video.ContentDetails != nil && video.ContentDetails.Duration != "" {
+		duration, err := parseYoutubeDuration(video.ContentDetails.Duration)
+		if err != nil {
+			return fmt.Errorf("parsing video duration: %w", err)
+		}
+		item.Duration = duration
+	}
+	return s.updateVideo(ctx, item, false)
+}
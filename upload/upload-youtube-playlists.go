@@ -2,6 +2,7 @@ package upload
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -40,6 +41,9 @@ func (s *Service) GetPlaylistsData() error {
 
 		fmt.Printf("Getting data for %d of %d playlists\n", end-i, len(playlistIds))
 
+		if err := s.spend("playlists.list"); err != nil {
+			return err
+		}
 		response, err := s.YoutubeService.Playlists.
 			List([]string{"snippet"}).
 			Id(playlistIds[i:end]...).
@@ -69,7 +73,7 @@ type PlaylistMeta struct {
 	Section    string `json:"s"`
 }
 
-func (s *Service) CreateOrUpdatePlaylists() error {
+func (s *Service) CreateOrUpdatePlaylists(ctx context.Context) error {
 	// find all the playlists which need to be updated
 	for _, expedition := range s.Expeditions {
 		if !expedition.Process {
@@ -78,17 +82,17 @@ func (s *Service) CreateOrUpdatePlaylists() error {
 		if expedition.ExpeditionPlaylist {
 			if expedition.Playlist == nil {
 				// create playlist
-				if err := s.createPlaylist(expedition); err != nil {
+				if err := s.createPlaylist(ctx, expedition); err != nil {
 					return fmt.Errorf("creating expedition playlist (%v): %w", expedition.Ref, err)
 				}
 			} else {
-				if err := s.updatePlaylist(expedition); err != nil {
+				if err := s.updatePlaylist(ctx, expedition); err != nil {
 					return fmt.Errorf("updating expedition playlist (%v): %w", expedition.Ref, err)
 				}
 			}
 		} else {
 			if expedition.Playlist != nil {
-				if err := s.deletePlaylist(expedition); err != nil {
+				if err := s.deletePlaylist(ctx, expedition); err != nil {
 					return fmt.Errorf("deleting expedition playlist (%v): %w", expedition.Ref, err)
 				}
 			}
@@ -96,11 +100,11 @@ func (s *Service) CreateOrUpdatePlaylists() error {
 		if expedition.SectionPlaylists {
 			for _, section := range expedition.Sections {
 				if section.Playlist == nil {
-					if err := s.createPlaylist(section); err != nil {
+					if err := s.createPlaylist(ctx, section); err != nil {
 						return fmt.Errorf("creating section playlist (%v, %v): %w", expedition.Ref, section.Ref, err)
 					}
 				} else {
-					if err := s.updatePlaylist(section); err != nil {
+					if err := s.updatePlaylist(ctx, section); err != nil {
 						return fmt.Errorf("updating section playlist (%v, %v): %w", expedition.Ref, section.Ref, err)
 					}
 				}
@@ -108,7 +112,7 @@ func (s *Service) CreateOrUpdatePlaylists() error {
 		} else {
 			for _, section := range expedition.Sections {
 				if section.Playlist != nil {
-					if err := s.deletePlaylist(section); err != nil {
+					if err := s.deletePlaylist(ctx, section); err != nil {
 						return fmt.Errorf("deleting section playlist (%v, %v): %w", expedition.Ref, section.Ref, err)
 					}
 				}
@@ -120,13 +124,7 @@ func (s *Service) CreateOrUpdatePlaylists() error {
 
 func (s *Service) getPlaylistDetails(parent HasPlaylist) (title, description string, content []*Item, err error) {
 	expedition := parent.GetExpedition()
-
-	templateData := map[string]any{}
-	templateData["Expedition"] = expedition
-	section, ok := parent.(*Section)
-	if ok {
-		templateData["Section"] = section
-	}
+	templateData := playlistTemplateData(parent)
 
 	titleBuffer := bytes.NewBufferString("")
 	if err := expedition.Templates.ExecuteTemplate(titleBuffer, "playlist_title", templateData); err != nil {
@@ -159,11 +157,23 @@ func (s *Service) getPlaylistDetails(parent HasPlaylist) (title, description str
 	return title, description, content, nil
 }
 
-func (s *Service) updatePlaylist(parent HasPlaylist) error {
+// playlistTemplateData is the data used to execute the playlist_title and playlist_description templates.
+func playlistTemplateData(parent HasPlaylist) map[string]any {
+	templateData := map[string]any{}
+	templateData["Expedition"] = parent.GetExpedition()
+	section, ok := parent.(*Section)
+	if ok {
+		templateData["Section"] = section
+	}
+	return templateData
+}
+
+func (s *Service) updatePlaylist(ctx context.Context, parent HasPlaylist) error {
 	playlist := parent.GetPlaylist()
 	title, description, content, err := s.getPlaylistDetails(parent)
 	if err != nil {
-		return fmt.Errorf("getting playlist details (%v): %w", parent.String(), err)
+		s.AddPlaylistProblem(parent, fmt.Errorf("getting playlist details: %w", err))
+		return nil
 	}
 
 	if s.Global.Preview {
@@ -173,11 +183,32 @@ func (s *Service) updatePlaylist(parent HasPlaylist) error {
 	if s.Global.Production {
 		if title != playlist.Snippet.Title || description != playlist.Snippet.Description {
 			// update playlist
+			liveHash, err := hashJSON(playlist.Snippet)
+			if err != nil {
+				return fmt.Errorf("hashing playlist (%v): %w", parent.String(), err)
+			}
+			before := copyPlaylist(playlist)
+			changes := map[string]Change{}
+			if title != playlist.Snippet.Title {
+				changes["title"] = Change{Before: playlist.Snippet.Title, After: title}
+			}
+			if description != playlist.Snippet.Description {
+				changes["description"] = Change{Before: playlist.Snippet.Description, After: description}
+			}
 			playlist.Snippet.Title = title
 			playlist.Snippet.Description = description
 			playlist.Snippet.DefaultLanguage = "en"
-			parts := []string{"snippet", "localizations", "status"}
-			if _, err := s.YoutubeService.Playlists.Update(parts, playlist).Do(); err != nil {
+			op := &Operation{
+				Kind:     OpPlaylistUpdate,
+				Subject:  parent.String(),
+				Target:   playlist.Id,
+				Parts:    []string{"snippet", "localizations", "status"},
+				Changes:  changes,
+				LiveHash: liveHash,
+				Playlist: playlist,
+				Before:   &Before{Playlist: before},
+			}
+			if _, err := s.execute(ctx, op); err != nil {
 				return fmt.Errorf("updating playlist (%v): %w", parent.String(), err)
 			}
 		}
@@ -200,7 +231,7 @@ func (s *Service) updatePlaylist(parent HasPlaylist) error {
 	}
 	if changed {
 		// sync the youtube playlist
-		if err := s.syncPlaylist(parent, content, playlistItems); err != nil {
+		if err := s.syncPlaylist(ctx, parent, content, playlistItems); err != nil {
 			return fmt.Errorf("syncing playlist (%v): %w", parent.String(), err)
 		}
 	} else {
@@ -219,6 +250,9 @@ func (s *Service) listPlaylistsItems(playlistId string) ([]*youtube.PlaylistItem
 	itemsByVideoId := map[string]*youtube.PlaylistItem{}
 
 	for !done {
+		if err := s.spend("playlistItems.list"); err != nil {
+			return nil, err
+		}
 		playlistResponse, err := s.YoutubeService.PlaylistItems.
 			List([]string{"snippet"}).
 			PlaylistId(playlistId).
@@ -288,11 +322,17 @@ func lcs(input []*Item, output []*youtube.PlaylistItem) []*Item {
 	return lcsList
 }
 
-func (s *Service) syncPlaylist(parent HasPlaylist, input []*Item, output []*youtube.PlaylistItem) error {
+func (s *Service) syncPlaylist(ctx context.Context, parent HasPlaylist, input []*Item, output []*youtube.PlaylistItem) error {
 	playlistId := parent.GetPlaylistId()
 	var ops []string
 	lcsList := lcs(input, output)
 
+	// the plan is checked against this when it's applied
+	liveHash, err := livePlaylistItemsHash(output)
+	if err != nil {
+		return fmt.Errorf("hashing playlist items (%v): %w", parent.String(), err)
+	}
+
 	// Step 1: Delete items in output that are NOT in LCS
 	lcsSet := make(map[string]bool)
 	for _, v := range lcsList {
@@ -307,9 +347,15 @@ func (s *Service) syncPlaylist(parent HasPlaylist, input []*Item, output []*yout
 				ops = append(ops, fmt.Sprintf("delete at %d (%s)", i, videoId))
 			}
 			if s.Global.Production {
-				fmt.Printf("Deleting playlist item: %s\n", videoId)
-				if err := s.YoutubeService.PlaylistItems.Delete(item.Id).Do(); err != nil {
-					return fmt.Errorf("failed to delete video %s: %v", videoId, err)
+				op := &Operation{
+					Kind:         OpPlaylistRemove,
+					Subject:      parent.String(),
+					Target:       item.Id,
+					LiveHash:     liveHash,
+					PlaylistItem: item,
+				}
+				if _, err := s.execute(ctx, op); err != nil {
+					return fmt.Errorf("failed to delete video %s: %w", videoId, err)
 				}
 			}
 		}
@@ -329,22 +375,30 @@ func (s *Service) syncPlaylist(parent HasPlaylist, input []*Item, output []*yout
 			}
 			if s.Global.Production {
 				fmt.Printf("Inserting playlist item: %s at position %d\n", v.YoutubeId, outputIndex)
-				pli, err := s.YoutubeService.PlaylistItems.Insert([]string{"snippet"}, &youtube.PlaylistItem{
-					Snippet: &youtube.PlaylistItemSnippet{
-						PlaylistId: playlistId,
-						ResourceId: &youtube.ResourceId{
-							Kind:    "youtube#video",
-							VideoId: v.YoutubeId,
-						},
-					},
-				}).Do()
+				op := &Operation{
+					Kind:         OpPlaylistInsert,
+					Subject:      parent.String(),
+					LiveHash:     liveHash,
+					PlaylistItem: newPlaylistItem(playlistId, v.YoutubeId),
+				}
+				pliId, err := s.execute(ctx, op)
 				if err != nil {
 					return fmt.Errorf("failed to insert playlist (%v) item %s: %w", parent.String(), v.YoutubeId, err)
 				}
 				// Position is ignored when inserting, must do an update fix.
+				pli := newPlaylistItem(playlistId, v.YoutubeId)
+				pli.Id = pliId
 				pli.Snippet.Position = int64(outputIndex)
-				if _, err := s.YoutubeService.PlaylistItems.Update([]string{"snippet"}, pli).Do(); err != nil {
-					return fmt.Errorf("failed to update playlist (%v) item %s: %w", parent.String(), pli.Id, err)
+				pli.Snippet.ForceSendFields = []string{"Position"}
+				op = &Operation{
+					Kind:         OpPlaylistMove,
+					Subject:      parent.String(),
+					Target:       pliId,
+					LiveHash:     liveHash,
+					PlaylistItem: pli,
+				}
+				if _, err := s.execute(ctx, op); err != nil {
+					return fmt.Errorf("failed to update playlist (%v) item %s: %w", parent.String(), pliId, err)
 				}
 			}
 			outputIndex++ // Advance since we inserted
@@ -357,10 +411,11 @@ func (s *Service) syncPlaylist(parent HasPlaylist, input []*Item, output []*yout
 	return nil
 }
 
-func (s *Service) createPlaylist(parent HasPlaylist) error {
+func (s *Service) createPlaylist(ctx context.Context, parent HasPlaylist) error {
 	title, description, content, err := s.getPlaylistDetails(parent)
 	if err != nil {
-		return fmt.Errorf("getting playlist details (%v): %w", parent.String(), err)
+		s.AddPlaylistProblem(parent, fmt.Errorf("getting playlist details: %w", err))
+		return nil
 	}
 
 	if s.Global.Preview {
@@ -383,43 +438,37 @@ func (s *Service) createPlaylist(parent HasPlaylist) error {
 				PrivacyStatus: "public", // or "private" or "unlisted"
 			},
 		}
-		fmt.Println("Creating playlist")
-		call := s.YoutubeService.Playlists.Insert(
-			[]string{"snippet", "status"},
-			playlist,
-		)
-		newPlaylist, err := call.Do()
+		op := &Operation{
+			Kind:     OpPlaylistCreate,
+			Subject:  parent.String(),
+			Changes:  map[string]Change{"title": {After: title}, "description": {After: description}},
+			Playlist: playlist,
+			Cell:     s.playlistCell(parent),
+		}
+		playlistId, err := s.execute(ctx, op)
 		if err != nil {
 			return fmt.Errorf("creating playlist (%v): %w", parent.String(), err)
 		}
+		// when planning, the id is a planned id
+		newPlaylist := op.Playlist
+		newPlaylist.Id = playlistId
 		switch parent := parent.(type) {
 		case *Expedition:
-			if err := s.Sheets["expedition"].Set(s.SheetsService, parent.RowId, "playlist_id", newPlaylist.Id, false); err != nil {
-				return fmt.Errorf("setting playlist_id for expedition %v: %w", parent.Ref, err)
-			}
 			parent.PlaylistId = newPlaylist.Id
 			parent.Playlist = newPlaylist
 		case *Section:
-			if err := parent.Expedition.Sheets["section"].Set(s.SheetsService, parent.RowId, "playlist_id", newPlaylist.Id, false); err != nil {
-				return fmt.Errorf("setting playlist_id for section %v: %w", parent.Ref, err)
-			}
 			parent.PlaylistId = newPlaylist.Id
 			parent.Playlist = newPlaylist
 		}
 
 		for _, item := range content {
-			playlistItem := &youtube.PlaylistItem{
-				Snippet: &youtube.PlaylistItemSnippet{
-					PlaylistId: newPlaylist.Id,
-					ResourceId: &youtube.ResourceId{
-						Kind:    "youtube#video",
-						VideoId: item.YoutubeId,
-					},
-					//Position: i, // do we need this?
-				},
-			}
 			fmt.Println("Creating playlist item for", item.YoutubeId)
-			if _, err := s.YoutubeService.PlaylistItems.Insert([]string{"snippet"}, playlistItem).Do(); err != nil {
+			op := &Operation{
+				Kind:         OpPlaylistInsert,
+				Subject:      parent.String(),
+				PlaylistItem: newPlaylistItem(newPlaylist.Id, item.YoutubeId),
+			}
+			if _, err := s.execute(ctx, op); err != nil {
 				return fmt.Errorf("inserting playlist item (%v): %w", parent.String(), err)
 			}
 		}
@@ -427,27 +476,32 @@ func (s *Service) createPlaylist(parent HasPlaylist) error {
 	return nil
 }
 
-func (s *Service) deletePlaylist(parent HasPlaylist) error {
+func (s *Service) deletePlaylist(ctx context.Context, parent HasPlaylist) error {
 	playlist := parent.GetPlaylist()
 	if s.Global.Preview {
 		s.StorePlaylistPreviewDeleted(parent)
 	}
 	if s.Global.Production {
-		fmt.Println("Deleting playlist", parent.GetPlaylistId())
-		if err := s.YoutubeService.Playlists.Delete(playlist.Id).Do(); err != nil {
+		liveHash, err := hashJSON(playlist.Snippet)
+		if err != nil {
+			return fmt.Errorf("hashing playlist (%v): %w", parent.String(), err)
+		}
+		op := &Operation{
+			Kind:     OpPlaylistDelete,
+			Subject:  parent.String(),
+			Target:   playlist.Id,
+			LiveHash: liveHash,
+			Cell:     s.playlistCell(parent),
+			Before:   &Before{Playlist: copyPlaylist(playlist)},
+		}
+		if _, err := s.execute(ctx, op); err != nil {
 			return fmt.Errorf("deleting playlist (%v): %w", parent.String(), err)
 		}
 		switch parent := parent.(type) {
 		case *Expedition:
-			if err := s.Sheets["expedition"].Clear(s.SheetsService, parent.RowId, "playlist_id"); err != nil {
-				return fmt.Errorf("clearing playlist_id for expedition %v: %w", parent.Ref, err)
-			}
 			parent.PlaylistId = ""
 			parent.Playlist = nil
 		case *Section:
-			if err := parent.Expedition.Sheets["section"].Clear(s.SheetsService, parent.RowId, "playlist_id"); err != nil {
-				return fmt.Errorf("clearing playlist_id for section %v: %w", parent.Ref, err)
-			}
 			parent.PlaylistId = ""
 			parent.Playlist = nil
 		}
@@ -455,6 +509,30 @@ func (s *Service) deletePlaylist(parent HasPlaylist) error {
 	return nil
 }
 
+// newPlaylistItem returns a playlist item for a video.
+func newPlaylistItem(playlistId, videoId string) *youtube.PlaylistItem {
+	return &youtube.PlaylistItem{
+		Snippet: &youtube.PlaylistItemSnippet{
+			PlaylistId: playlistId,
+			ResourceId: &youtube.ResourceId{
+				Kind:    "youtube#video",
+				VideoId: videoId,
+			},
+		},
+	}
+}
+
+// playlistCell returns the playlist_id cell of the expedition or section.
+func (s *Service) playlistCell(parent HasPlaylist) *CellRef {
+	switch parent := parent.(type) {
+	case *Expedition:
+		return cellRef(s.Sheets["expedition"], parent.RowId, "playlist_id")
+	case *Section:
+		return cellRef(parent.Expedition.Sheets["section"], parent.RowId, "playlist_id")
+	}
+	return nil
+}
+
 type HasPlaylist interface {
 	GetPlaylistId() string
 	GetPlaylist() *youtube.Playlist
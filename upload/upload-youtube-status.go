@@ -0,0 +1,118 @@
+package upload
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// StatusFields are the video status and compliance settings. They're read with item.Setting, so a value in
+// the item sheet overrides the expedition sheet, which overrides the global sheet. Settings that aren't
+// set anywhere are nil, and the value on YouTube is left alone.
+type StatusFields struct {
+	MadeForKids          *bool   // made_for_kids
+	License              *string // license: "youtube" or "creativeCommon"
+	Embeddable           *bool   // embeddable
+	PublicStatsViewable  *bool   // public_stats_viewable
+	SyntheticMedia       *bool   // synthetic_media
+	PaidProductPlacement *bool   // paid_product_placement
+}
+
+func (item *Item) statusFields() (StatusFields, error) {
+	boolSetting := func(name string) *bool {
+		if item.Setting(name).Empty() {
+			return nil
+		}
+		v := item.Setting(name).Bool()
+		return &v
+	}
+	var license *string
+	if !item.Setting("license").Empty() {
+		v := item.Setting("license").String()
+		if v != "youtube" && v != "creativeCommon" {
+			return StatusFields{}, fmt.Errorf("license must be youtube or creativeCommon, found %q", v)
+		}
+		license = &v
+	}
+	return StatusFields{
+		MadeForKids:          boolSetting("made_for_kids"),
+		License:              license,
+		Embeddable:           boolSetting("embeddable"),
+		PublicStatsViewable:  boolSetting("public_stats_viewable"),
+		SyntheticMedia:       boolSetting("synthetic_media"),
+		PaidProductPlacement: boolSetting("paid_product_placement"),
+	}, nil
+}
+
+// applyStatus sets the status and compliance fields on the video.
+func (y *YoutubeFields) applyStatus(video *youtube.Video, c *Changes) {
+	// videos that haven't been uploaded yet only show and send the fields that are set in the sheet
+	existing := video.Id != ""
+	c.Status = Change{Before: y.Status.format(video, existing)}
+
+	setBool := func(part string, current *bool, value *bool) {
+		if value != nil && *current != *value {
+			c.change(part)
+			*current = *value
+		}
+	}
+	setBool("status", &video.Status.SelfDeclaredMadeForKids, y.Status.MadeForKids)
+	setBool("status", &video.Status.Embeddable, y.Status.Embeddable)
+	setBool("status", &video.Status.PublicStatsViewable, y.Status.PublicStatsViewable)
+	setBool("status", &video.Status.ContainsSyntheticMedia, y.Status.SyntheticMedia)
+	if y.Status.License != nil && video.Status.License != *y.Status.License {
+		c.change("status")
+		video.Status.License = *y.Status.License
+	}
+	if y.Status.PaidProductPlacement != nil {
+		if video.PaidProductPlacementDetails == nil {
+			video.PaidProductPlacementDetails = &youtube.VideoPaidProductPlacementDetails{}
+		}
+		setBool("paidProductPlacementDetails", &video.PaidProductPlacementDetails.HasPaidProductPlacement, y.Status.PaidProductPlacement)
+		// false must be sent, or it's omitted from the request
+		video.PaidProductPlacementDetails.ForceSendFields = []string{"HasPaidProductPlacement"}
+	}
+
+	// The status part is sent in full on update, so false values must be sent too, or YouTube resets them
+	// to the defaults.
+	video.Status.ForceSendFields = nil
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"SelfDeclaredMadeForKids", y.Status.MadeForKids != nil},
+		{"Embeddable", y.Status.Embeddable != nil},
+		{"PublicStatsViewable", y.Status.PublicStatsViewable != nil},
+		{"ContainsSyntheticMedia", y.Status.SyntheticMedia != nil},
+	} {
+		if existing || field.set {
+			video.Status.ForceSendFields = append(video.Status.ForceSendFields, field.name)
+		}
+	}
+
+	c.Status.After = y.Status.format(video, existing)
+}
+
+// format lists the status fields of the video for the preview. If all is false, only the fields set in the
+// sheet are listed.
+func (f StatusFields) format(video *youtube.Video, all bool) string {
+	if video.Status == nil {
+		return ""
+	}
+	var lines []string
+	add := func(set bool, name string, value any) {
+		if all || set {
+			lines = append(lines, fmt.Sprintf("%s: %v", name, value))
+		}
+	}
+	add(f.MadeForKids != nil, "made_for_kids", video.Status.SelfDeclaredMadeForKids)
+	add(f.License != nil, "license", video.Status.License)
+	add(f.Embeddable != nil, "embeddable", video.Status.Embeddable)
+	add(f.PublicStatsViewable != nil, "public_stats_viewable", video.Status.PublicStatsViewable)
+	add(f.SyntheticMedia != nil, "synthetic_media", video.Status.ContainsSyntheticMedia)
+	if video.PaidProductPlacementDetails != nil {
+		add(f.PaidProductPlacement != nil, "paid_product_placement", video.PaidProductPlacementDetails.HasPaidProductPlacement)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -2,6 +2,7 @@ package upload
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
@@ -20,7 +21,7 @@ import (
 	"google.golang.org/api/drive/v3"
 )
 
-func (s *Service) UpdateThumbnails() error {
+func (s *Service) UpdateThumbnails(ctx context.Context) error {
 	// find all the videos which need to be updated
 	if !s.Global.Thumbnails {
 		return nil
@@ -40,7 +41,7 @@ func (s *Service) UpdateThumbnails() error {
 				// if we're not in preview mode, we can only update the thumbnail if the video has been uploaded
 				continue
 			}
-			if err := updateThumbnail(s, item); err != nil {
+			if err := updateThumbnail(ctx, s, item); err != nil {
 				return fmt.Errorf("updating thumbnail (%v): %w", item.String(), err)
 			}
 		}
@@ -48,15 +49,17 @@ func (s *Service) UpdateThumbnails() error {
 	return nil
 }
 
-func updateThumbnail(s *Service, item *Item) error {
+func updateThumbnail(ctx context.Context, s *Service, item *Item) error {
 
 	textTopBuffer := bytes.NewBufferString("")
-	if err := item.Expedition.Templates.ExecuteTemplate(textTopBuffer, "thumbnail_top", item); err != nil {
-		return fmt.Errorf("execute thumbnail top template (%v): %w", item.String(), err)
+	if err := item.Templates.ExecuteTemplate(textTopBuffer, "thumbnail_top", item); err != nil {
+		s.AddItemProblem(item, "thumbnail", fmt.Errorf("execute thumbnail top template: %w", err))
+		return nil
 	}
 	textBottomBuffer := bytes.NewBufferString("")
-	if err := item.Expedition.Templates.ExecuteTemplate(textBottomBuffer, "thumbnail_bottom", item); err != nil {
-		return fmt.Errorf("execute thumbnail top template (%v): %w", item.String(), err)
+	if err := item.Templates.ExecuteTemplate(textBottomBuffer, "thumbnail_bottom", item); err != nil {
+		s.AddItemProblem(item, "thumbnail", fmt.Errorf("execute thumbnail bottom template: %w", err))
+		return nil
 	}
 
 	fmt.Printf("Updating thumbnail (%v)\n", item.String())
@@ -115,7 +118,19 @@ func updateThumbnail(s *Service, item *Item) error {
 		if item.YoutubeVideo == nil {
 			return fmt.Errorf("item has no youtube video (%v)", item.String())
 		}
-		if _, err := s.YoutubeService.Thumbnails.Set(item.YoutubeVideo.Id).Media(bytes.NewReader(transformedBytes)).Do(); err != nil {
+		hash, err := hashJSON(transformedBytes)
+		if err != nil {
+			return fmt.Errorf("hashing thumbnail (%v): %w", item.String(), err)
+		}
+		op := &Operation{
+			Kind:       OpThumbnailSet,
+			Subject:    item.String(),
+			Target:     item.YoutubeVideo.Id,
+			SourceHash: hash,
+			Thumbnail:  transformedBytes,
+			Before:     &Before{ThumbnailURL: thumbnailURL(item.YoutubeVideo)},
+		}
+		if _, err := s.execute(ctx, op); err != nil {
 			return fmt.Errorf("setting thumbnail (%v): %w", item.String(), err)
 		}
 	}
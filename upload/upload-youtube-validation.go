@@ -0,0 +1,233 @@
+package upload
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// YouTube rejects video metadata over these limits.
+const (
+	maxTitleLength       = 100  // characters
+	maxDescriptionLength = 5000 // bytes
+	maxTagsLength        = 500  // characters, including commas and the quotes around tags containing spaces
+)
+
+// ValidationIssue is a broken YouTube rule found in the rendered metadata of a video. Errors stop the
+// video being created or updated, warnings are shown in the preview.
+type ValidationIssue struct {
+	Warning bool
+	Field   string // title, description, tags or chapters
+	Message string
+	Chapter *Chapter // the chapter with the problem, for chapter issues
+}
+
+func (v ValidationIssue) String() string {
+	if v.Warning {
+		return fmt.Sprintf("warning: %s: %s", v.Field, v.Message)
+	}
+	return fmt.Sprintf("error: %s: %s", v.Field, v.Message)
+}
+
+// Column returns the item sheet column most likely to need changing to fix the issue.
+func (v ValidationIssue) Column() string {
+	switch {
+	case v.Field == "description":
+		return "template"
+	case v.Field == "tags":
+		return "tags"
+	case strings.HasPrefix(v.Field, "title.") || strings.HasPrefix(v.Field, "description."):
+		// the translation column, if it exists
+		return v.Field
+	default:
+		return "key"
+	}
+}
+
+// Validate checks the fields against the YouTube limits.
+func (y *YoutubeFields) Validate() []ValidationIssue {
+	var issues []ValidationIssue
+	fail := func(field, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(field, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Warning: true, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(y.Title) == "" {
+		fail("title", "title is empty")
+	}
+	if length := utf8.RuneCountInString(y.Title); length > maxTitleLength {
+		fail("title", "title is %d characters, the maximum is %d", length, maxTitleLength)
+	}
+	if strings.ContainsAny(y.Title, "<>") {
+		fail("title", "title contains angle brackets")
+	}
+
+	if y.TruncatedFrom > 0 {
+		warn("description", "description truncated from %d to %d bytes", y.TruncatedFrom, len(y.Description))
+	}
+	if length := len(y.Description); length > maxDescriptionLength {
+		fail("description", "description is %d bytes, the maximum is %d", length, maxDescriptionLength)
+	}
+	if strings.ContainsAny(y.Description, "<>") {
+		fail("description", "description contains angle brackets")
+	}
+
+	var langs []string
+	for lang := range y.Localizations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		localization := y.Localizations[lang]
+		if length := utf8.RuneCountInString(localization.Title); length > maxTitleLength {
+			fail("title."+lang, "title is %d characters, the maximum is %d", length, maxTitleLength)
+		}
+		if strings.ContainsAny(localization.Title, "<>") {
+			fail("title."+lang, "title contains angle brackets")
+		}
+		if length := len(localization.Description); length > maxDescriptionLength {
+			fail("description."+lang, "description is %d bytes, the maximum is %d", length, maxDescriptionLength)
+		}
+		if strings.ContainsAny(localization.Description, "<>") {
+			fail("description."+lang, "description contains angle brackets")
+		}
+	}
+	for _, lang := range y.MissingLanguages {
+		warn("languages", "no title or description translation for %s", lang)
+	}
+
+	// a video that can be watched now, or that YouTube will publish at its release time without another
+	// run, mustn't be published with a placeholder instead of a link
+	public := y.privacy() != "private" || y.scheduled()
+	fields := []struct{ name, value string }{{"title", y.Title}, {"description", y.Description}}
+	for _, lang := range langs {
+		fields = append(fields,
+			struct{ name, value string }{"title." + lang, y.Localizations[lang].Title},
+			struct{ name, value string }{"description." + lang, y.Localizations[lang].Description},
+		)
+	}
+	for _, field := range fields {
+		for _, match := range PendingLinkRegex.FindAllStringSubmatch(field.value, -1) {
+			if public {
+				fail(field.name, "pending link to %s in a public or scheduled video", match[1])
+			} else {
+				warn(field.name, "pending link to %s", match[1])
+			}
+		}
+	}
+
+	if length := tagsLength(y.Tags); length > maxTagsLength {
+		fail("tags", "tags are %d characters, the maximum is %d", length, maxTagsLength)
+	}
+
+	return issues
+}
+
+// tagsLength returns the length of the tags as YouTube counts it: the tags are joined with commas, and
+// tags containing spaces are quoted.
+func tagsLength(tags []string) int {
+	var length, count int
+	for _, tag := range tags {
+		if tag == "" {
+			continue
+		}
+		length += utf8.RuneCountInString(tag)
+		if strings.Contains(tag, " ") {
+			length += 2
+		}
+		count++
+	}
+	if count > 1 {
+		length += count - 1
+	}
+	return length
+}
+
+// truncateWords shortens s to at most max bytes, cutting at a word boundary and adding an ellipsis.
+func truncateWords(s string, max int) string {
+	const ellipsis = "…"
+	if len(s) <= max {
+		return s
+	}
+	max -= len(ellipsis)
+	if max <= 0 {
+		return ""
+	}
+	// don't cut a multi-byte character in half
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	cut := s[:max]
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 && !unicode.IsSpace(rune(s[max])) {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace) + ellipsis
+}
+
+// ValidateVideos renders the metadata of all videos and checks it against the YouTube limits, before any
+// videos are created or updated. Videos with errors are recorded as problems and skipped.
+func (s *Service) ValidateVideos() error {
+	for _, expedition := range s.Expeditions {
+		if !expedition.Process {
+			continue
+		}
+		for _, item := range expedition.Items {
+			if !item.Video {
+				continue
+			}
+			s.validateVideo(item, s.Global.Preview)
+		}
+	}
+	return nil
+}
+
+func (s *Service) validateVideo(item *Item, preview bool) {
+	fields, err := apply(item)
+	if err != nil {
+		// template errors are recorded when the video is updated
+		return
+	}
+	// the problems from an earlier validation are replaced
+	s.RemoveProblems(item.ValidationProblems)
+	item.ValidationProblems = nil
+	item.Validation = append(fields.Validate(), item.ValidateChapters()...)
+
+	var lines []string
+	for _, issue := range item.Validation {
+		lines = append(lines, issue.String())
+		if issue.Warning {
+			continue
+		}
+		err := fmt.Errorf("invalid %s: %s", issue.Field, issue.Message)
+		if issue.Chapter != nil {
+			s.AddProblem(item.Expedition.Sheets[chapterSheetName], issue.Chapter.RowId, chapterTimeColumn, item.String(), err)
+		} else {
+			s.AddItemProblem(item, issue.Column(), err)
+		}
+		item.ValidationProblems = append(item.ValidationProblems, s.Problems[len(s.Problems)-1])
+	}
+	if preview {
+		if _, ok := s.VideoPreviewData[item]; !ok {
+			s.VideoPreviewData[item] = map[string]any{}
+		}
+		if len(lines) == 0 {
+			s.VideoPreviewData[item]["video_validation"] = "=== VALID ==="
+		} else {
+			s.VideoPreviewData[item]["video_validation"] = strings.Join(lines, "\n")
+		}
+	}
+}
+
+// Invalid returns true if validation found errors in the rendered metadata.
+func (item *Item) Invalid() bool {
+	for _, issue := range item.Validation {
+		if !issue.Warning {
+			return true
+		}
+	}
+	return false
+}
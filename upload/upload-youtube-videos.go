@@ -10,7 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/dave/youtube/resume"
 	"google.golang.org/api/youtube/v3"
 )
 
@@ -108,6 +107,9 @@ func (s *Service) GetVideosCaptions() error {
 				return nil
 			}
 			fmt.Println("Getting captions for", item.String())
+			if err := s.spend("captions.list"); err != nil {
+				return err
+			}
 			captionsListResponse, err := s.YoutubeService.Captions.List([]string{"id", "snippet"}, item.YoutubeId).Do()
 			if err != nil {
 				return fmt.Errorf("youtube captions list call (%v): %w", item.String(), err)
@@ -127,6 +129,9 @@ func (s *Service) GetVideosCaptions() error {
 				fmt.Printf("Could not find english captions (%v), using %s instead.\n", item.String(), captionsListResponse.Items[0].Snippet.Language)
 				captionsId = captionsListResponse.Items[0].Id
 			}
+			if err := s.spend("captions.download"); err != nil {
+				return err
+			}
 			captionsDownloadResponse, err := s.YoutubeService.Captions.Download(captionsId).Tfmt("ttml").Download()
 			if err != nil {
 				return fmt.Errorf("youtube captions download call (%v): %w", item.String(), err)
@@ -206,8 +211,6 @@ func (s *Service) GetVideosData() error {
 		}
 	}
 
-	apiPartsRead := []string{"snippet", "localizations", "status", "fileDetails"}
-
 	const maxBatchSize = 50
 
 	for i := 0; i < len(videoIds); i += maxBatchSize {
@@ -218,8 +221,11 @@ func (s *Service) GetVideosData() error {
 
 		fmt.Printf("Getting data for %d of %d videos\n", end-i, len(videoIds))
 
+		if err := s.spend("videos.list"); err != nil {
+			return err
+		}
 		response, err := s.YoutubeService.Videos.
-			List(apiPartsRead).
+			List(videoPartsRead).
 			Id(videoIds[i:end]...).
 			Do()
 		if err != nil {
@@ -229,22 +235,37 @@ func (s *Service) GetVideosData() error {
 			return fmt.Errorf("video list response length mismatch response: %d, request: %d)", len(response.Items), end-i)
 		}
 		for _, video := range response.Items {
-			itemsMap[video.Id].YoutubeVideo = video
+			item := itemsMap[video.Id]
+			item.YoutubeVideo = video
+			if video.ContentDetails != nil && video.ContentDetails.Duration != "" {
+				duration, err := parseYoutubeDuration(video.ContentDetails.Duration)
+				if err != nil {
+					return fmt.Errorf("parsing video duration (%v): %w", item.String(), err)
+				}
+				item.Duration = duration
+			}
 		}
 	}
 
 	return nil
 }
 
+// videoPartsRead are the parts read from the YouTube API for each video.
+var videoPartsRead = []string{"snippet", "localizations", "status", "contentDetails", "recordingDetails", "paidProductPlacementDetails"}
+
+// VideoMeta is encoded in the block at the end of the video description. Version 2 added the hash of the
+// rendered fields.
 type VideoMeta struct {
 	Version    int    `json:"v"`
 	Expedition string `json:"e"`
 	Type       string `json:"t"`
 	Key        int    `json:"k"`
+	Hash       string `json:"h,omitempty"`
 }
 
 func (s *Service) CreateOrUpdateVideos(ctx context.Context) error {
 	// find all the videos which need to be updated
+	var created int
 	for _, expedition := range s.Expeditions {
 		if !expedition.Process {
 			continue
@@ -258,37 +279,121 @@ func (s *Service) CreateOrUpdateVideos(ctx context.Context) error {
 				if err := s.createVideo(ctx, item); err != nil {
 					return fmt.Errorf("updating video (%v): %w", item.String(), err)
 				}
+				if item.YoutubeVideo != nil {
+					created++
+				}
 			} else {
-				if err := s.updateVideo(item); err != nil {
+				if err := s.updateVideo(ctx, item, s.Global.Preview); err != nil {
 					return fmt.Errorf("updating video (%v): %w", item.String(), err)
 				}
 			}
 		}
 	}
+
+	// Videos created in this run have ids now, so templates using {{.Next.URL}} render differently. In
+	// two-pass mode, all the videos are rendered again and updated if they've changed.
+	if created > 0 && s.Global.TwoPass {
+		fmt.Printf("Created %d videos, updating links in the second pass\n", created)
+		for _, expedition := range s.Expeditions {
+			if !expedition.Process {
+				continue
+			}
+			for _, item := range expedition.Items {
+				if !item.Video || item.YoutubeVideo == nil {
+					continue
+				}
+				if _, err := apply(item); err != nil {
+					// recorded in the first pass
+					continue
+				}
+				// the new links may resolve pending links that failed validation in the first pass, or take
+				// the metadata over the limits
+				item.Validation = nil
+				if s.validateVideo(item, s.Global.Preview); item.Invalid() {
+					continue
+				}
+				if err := s.updateVideo(ctx, item, false); err != nil {
+					return fmt.Errorf("updating video in second pass (%v): %w", item.String(), err)
+				}
+			}
+		}
+	}
 	return nil
 }
 
-func (s *Service) updateVideo(item *Item) error {
+// updateVideo applies the item data to the video, and updates it if anything changed. The changes are
+// stored in the preview if preview is true.
+func (s *Service) updateVideo(ctx context.Context, item *Item, preview bool) error {
 
-	changes, err := Apply(item, item.YoutubeVideo)
+	fields, err := apply(item)
 	if err != nil {
-		return fmt.Errorf("applying data (%v): %w", item.String(), err)
+		s.AddItemProblem(item, "template", fmt.Errorf("applying data: %w", err))
+		return nil
+	}
+	synced, drift, err := s.resolveDrift(item, &fields, item.YoutubeVideo)
+	if err != nil {
+		return fmt.Errorf("resolving drift (%v): %w", item.String(), err)
+	}
+	for name, kind := range drift {
+		if kind == DriftConflict && !s.Force {
+			fmt.Printf("Conflict in %s, edited in the sheet and YouTube Studio (%v)\n", name, item.String())
+		}
+	}
+	// the plan is checked against this when it's applied
+	liveHash, err := liveVideoHash(item.YoutubeVideo)
+	if err != nil {
+		return fmt.Errorf("hashing video (%v): %w", item.String(), err)
+	}
+	// the video is changed by Apply, so it's copied for the journal
+	before, err := copyVideo(item.YoutubeVideo)
+	if err != nil {
+		return fmt.Errorf("copying video (%v): %w", item.String(), err)
+	}
+	var live VideoMeta
+	hasHash := item.YoutubeVideo.Snippet != nil && decodeMeta(item.YoutubeVideo.Snippet.Description, &live) && live.Hash != ""
+	changes := fields.Apply(item.YoutubeVideo)
+	skipped := hasHash && live.Hash == fields.Hash && !s.Force
+	if skipped {
+		// nothing has changed in the sheet since the last update, so differences in the live data (e.g.
+		// normalised by YouTube) aren't sent. Edits kept from YouTube Studio already have the live value.
+		changes.unchanged()
 	}
 
-	if s.Global.Preview {
+	if preview {
 		// store updated metadata
 		s.StoreVideoPreview(item, "video_title", changes.Title.Before, changes.Title.After)
 		s.StoreVideoPreview(item, "video_description", changes.Description.Before, changes.Description.After)
 		s.StoreVideoPreview(item, "video_privacy_status", changes.PrivacyStatus.Before, changes.PrivacyStatus.After)
-		s.StoreVideoPreview(item, "video_publish_at", changes.PublishAt.Before, changes.PublishAt.After)
+		s.StoreVideoPreview(item, "video_publish_at", youtubeTimeIn(changes.PublishAt.Before, item.TimeZone), youtubeTimeIn(changes.PublishAt.After, item.TimeZone))
 		s.StoreVideoPreview(item, "video_tags", changes.Tags.Before, changes.Tags.After)
+		s.StoreVideoPreview(item, "video_recording", changes.Recording.Before, changes.Recording.After)
+		s.StoreVideoPreview(item, "video_status", changes.Status.Before, changes.Status.After)
+		s.storeLocalizationsPreview(item, changes, false)
+		s.storeDriftPreview(item, drift)
+		if skipped {
+			s.VideoPreviewData[item]["video_drift"] = fmt.Sprintf("%s\nskipped, the sheet hasn't changed since the last update (the hash is unchanged)", s.VideoPreviewData[item]["video_drift"])
+		}
 	}
-	if s.Global.Production && item.Ready && changes.Changed {
-		fmt.Printf("Updating video (%v)\n", item.String())
-		// clear FileDetails because it's not updatable
-		item.YoutubeVideo.FileDetails = nil
-		parts := []string{"snippet", "localizations", "status"}
-		if _, err := s.YoutubeService.Videos.Update(parts, item.YoutubeVideo).Do(); err != nil {
+	if s.Global.Production && item.Ready && !item.Invalid() && !changes.Changed && s.Planning == nil {
+		if err := s.recordSync(item.YoutubeId, synced); err != nil {
+			return fmt.Errorf("recording sync state (%v): %w", item.String(), err)
+		}
+	}
+	if s.Global.Production && item.Ready && changes.Changed && !item.Invalid() {
+		parts, update := changes.update(item.YoutubeVideo)
+		op := &Operation{
+			Kind:       OpVideoUpdate,
+			Subject:    item.String(),
+			Target:     item.YoutubeId,
+			Parts:      parts,
+			Changes:    changes.values(),
+			SourceHash: fields.Hash,
+			LiveHash:   liveHash,
+			Video:      update,
+			Synced:     synced,
+			Before:     &Before{Video: before},
+		}
+		if _, err := s.execute(ctx, op); err != nil {
 			return fmt.Errorf("updating video (%v): %w", item.String(), err)
 		}
 	}
@@ -300,32 +405,47 @@ func (s *Service) createVideo(ctx context.Context, item *Item) error {
 
 	video := &youtube.Video{}
 
-	changes, err := Apply(item, video)
+	fields, err := apply(item)
 	if err != nil {
-		return fmt.Errorf("applying data (%v): %w", item.String(), err)
+		s.AddItemProblem(item, "template", fmt.Errorf("applying data: %w", err))
+		return nil
 	}
+	changes := fields.Apply(video)
 
 	if s.Global.Preview {
 		s.StoreVideoPreview(item, "video_title", "", changes.Title.After)
 		s.StoreVideoPreview(item, "video_description", "", changes.Description.After)
 		s.StoreVideoPreview(item, "video_privacy_status", "", changes.PrivacyStatus.After)
-		s.StoreVideoPreview(item, "video_publish_at", "", changes.PublishAt.After)
+		s.StoreVideoPreview(item, "video_publish_at", "", youtubeTimeIn(changes.PublishAt.After, item.TimeZone))
 		s.StoreVideoPreview(item, "video_tags", "", changes.Tags.After)
+		s.StoreVideoPreview(item, "video_recording", "", changes.Recording.After)
+		s.StoreVideoPreview(item, "video_status", "", changes.Status.After)
+		s.storeLocalizationsPreview(item, changes, true)
 	}
-	if s.Global.Production && item.Ready {
+	if s.Global.Production && item.Ready && !item.Invalid() {
 
-		res, err := s.getResume()
-		if err != nil {
-			return fmt.Errorf("getting uploader (%v): %w", item.String(), err)
-		}
-		if res.State == resume.StateUploadInProgress {
-			return fmt.Errorf("upload already in progress (%v)", item.String())
+		// an earlier run may have uploaded the video without writing the id to the sheet. Only items with the
+		// pending marker are checked, so a youtube_id cleared to upload a replacement isn't linked to the old
+		// video.
+		if item.PendingUpload {
+			uploaded, err := s.findUploadedVideo(item)
+			if err != nil {
+				return fmt.Errorf("checking recent uploads (%v): %w", item.String(), err)
+			}
+			if uploaded != nil {
+				return s.linkUploadedVideo(ctx, item, uploaded)
+			}
 		}
 
-		fmt.Printf("Uploading video (%s)\n", item.String())
-		progress := func(start int64) {
-			fmt.Printf(" - uploaded %d of %d bytes (%.2f%%)\n", start, res.ContentLength, float64(start)/float64(res.ContentLength)*100)
+		if s.UploadSlots == 0 {
+			fmt.Printf("Deferring upload to the next run, not enough quota left today (%v)\n", item.String())
+			s.DeferredUploads++
+			return nil
+		}
+		if s.UploadSlots > 0 {
+			s.UploadSlots--
 		}
+
 		var videoFileId string
 		switch s.StorageService {
 		case GoogleDriveStorage:
@@ -333,19 +453,29 @@ func (s *Service) createVideo(ctx context.Context, item *Item) error {
 		case DropboxStorage:
 			videoFileId = item.VideoDropbox.Id
 		}
-		if err := res.Initialise(videoFileId, video); err != nil {
-			return fmt.Errorf("initialising upload (%v): %w", item.String(), err)
+		synced := map[string]string{}
+		for _, field := range fields.syncFields(video) {
+			synced[field.name] = field.rendered
+		}
+		op := &Operation{
+			Kind:       OpVideoCreate,
+			Subject:    item.String(),
+			Changes:    changes.values(),
+			SourceHash: fields.Hash,
+			Video:      video,
+			File:       videoFileId,
+			Cell:       cellRef(item.Expedition.ItemSheet, item.RowId, "youtube_id"),
+			Synced:     synced,
 		}
-		insertedVideo, err := res.Upload(ctx, progress)
+		id, err := s.execute(ctx, op)
 		if err != nil {
 			return fmt.Errorf("uploading video (%v): %w", item.String(), err)
 		}
-
-		if err := item.Set(s, "youtube_id", insertedVideo.Id, false); err != nil {
-			return fmt.Errorf("setting youtube_id (%v): %w", item.String(), err)
-		}
-		item.YoutubeVideo = insertedVideo
-		item.YoutubeId = insertedVideo.Id
+		// when planning, the id is a planned id, and the video is the one that will be uploaded
+		item.YoutubeVideo = op.Video
+		item.YoutubeVideo.Id = id
+		item.YoutubeId = id
+		item.PendingUpload = false
 
 	}
 
@@ -354,31 +484,107 @@ func (s *Service) createVideo(ctx context.Context, item *Item) error {
 
 func apply(item *Item) (YoutubeFields, error) {
 
-	fields := DefaultYoutubeFields()
+	fields, err := item.youtubeFields()
+	if err != nil {
+		return YoutubeFields{}, fmt.Errorf("error reading video settings (%v): %w", item.String(), err)
+	}
 
 	fields.PublishAt = item.Release
 
 	bufDescription := &strings.Builder{}
-	if err := item.Expedition.Templates.ExecuteTemplate(bufDescription, item.Template, item); err != nil {
+	if err := item.Templates.ExecuteTemplate(bufDescription, item.Template, item); err != nil {
 		return YoutubeFields{}, fmt.Errorf("error executing description template (%v): %w", item.String(), err)
 	}
-	metadata, err := item.Metadata()
+	// the hash is a fixed length, so the length of the meta block is known before it's calculated
+	metadata, err := item.Metadata(strings.Repeat("0", hashLength))
 	if err != nil {
 		return YoutubeFields{}, fmt.Errorf("error getting metadata (%v): %w", item.String(), err)
 	}
-	fields.Description = strings.TrimSpace(bufDescription.String()) + "\n\n{" + metadata + "}"
+	description := strings.TrimSpace(bufDescription.String())
+	metaLength := len("\n\n{" + metadata + "}")
+	if item.Setting("truncate_description").Bool() && len(description)+metaLength > maxDescriptionLength {
+		// the meta block is kept intact, so the video can still be matched to the item
+		fields.TruncatedFrom = len(description) + metaLength
+		description = truncateWords(description, maxDescriptionLength-metaLength)
+	}
+	fields.Description = description
 
 	bufTitle := &strings.Builder{}
-	if err := item.Expedition.Templates.ExecuteTemplate(bufTitle, "title", item); err != nil {
+	if err := item.Templates.ExecuteTemplate(bufTitle, "title", item); err != nil {
 		return YoutubeFields{}, fmt.Errorf("error executing title template (%v): %w", item.String(), err)
 	}
 	fields.Title = bufTitle.String()
 
 	fields.Tags = item.Tags
 
+	if location, ok := item.RecordingLocation(); ok {
+		fields.RecordingLocation = &youtube.GeoPoint{Latitude: location.Lat, Longitude: location.Lon}
+	}
+	fields.RecordingDate = item.RecordingDate()
+
+	status, err := item.statusFields()
+	if err != nil {
+		return YoutubeFields{}, fmt.Errorf("error reading status settings (%v): %w", item.String(), err)
+	}
+	fields.Status = status
+
+	for _, lang := range item.Languages(fields.DefaultLanguage) {
+		localization, ok, err := item.localize(lang, fields.Title, description)
+		if err != nil {
+			return YoutubeFields{}, fmt.Errorf("error localizing (%v, %v): %w", item.String(), lang, err)
+		}
+		if !ok {
+			fields.MissingLanguages = append(fields.MissingLanguages, lang)
+			continue
+		}
+		if fields.Localizations == nil {
+			fields.Localizations = map[string]youtube.VideoLocalization{}
+		}
+		fields.Localizations[lang] = localization
+	}
+
+	hash, err := fields.hash()
+	if err != nil {
+		return YoutubeFields{}, fmt.Errorf("error hashing fields (%v): %w", item.String(), err)
+	}
+	fields.Hash = hash
+	metadata, err = item.Metadata(hash)
+	if err != nil {
+		return YoutubeFields{}, fmt.Errorf("error getting metadata (%v): %w", item.String(), err)
+	}
+	fields.Description = description + "\n\n{" + metadata + "}"
+
 	return fields, nil
 }
 
+// hashLength is the length of the hash of the rendered fields in the video metadata block.
+const hashLength = 16
+
+// hash returns a hash of the rendered fields. It's stored in the metadata block at the end of the
+// description, so if it's unchanged on the next run, the video doesn't need to be updated. The privacy is
+// included as it will be after the changes are applied, so the video is updated when it's released.
+func (y *YoutubeFields) hash() (string, error) {
+	var publishAt string
+	if y.scheduled() {
+		publishAt = timeToYoutube(y.PublishAt)
+	}
+	return hashJSON(map[string]any{
+		"title":                  y.Title,
+		"description":            y.Description,
+		"tags":                   y.Tags,
+		"category_id":            y.CategoryId,
+		"channel_id":             y.ChannelId,
+		"default_language":       y.DefaultLanguage,
+		"default_audio_language": y.DefaultAudioLanguage,
+		"privacy_status":         y.privacy(),
+		"publish_at":             publishAt,
+		"localizations":          y.Localizations,
+		"recording_location":     y.RecordingLocation,
+		"recording_date":         y.RecordingDate,
+		"status":                 y.Status,
+	})
+}
+
 func Apply(item *Item, video *youtube.Video) (changes Changes, err error) {
 	fields, err := apply(item)
 	if err != nil {
@@ -388,35 +594,167 @@ func Apply(item *Item, video *youtube.Video) (changes Changes, err error) {
 }
 
 type YoutubeFields struct {
-	PrivacyStatus        string    // privacy status before PublishAt time. After this time, it is always public.
-	PublishAt            time.Time // no default
-	CategoryId           string
-	ChannelId            string
-	DefaultAudioLanguage string
-	DefaultLanguage      string
-	LiveBroadcastContent string
-	Description          string // no default
-	Title                string // no default
-	Tags                 []string
+	PrivacyStatus         string    // privacy status before PublishAt time
+	ReleasedPrivacyStatus string    // privacy status after PublishAt time
+	PublishAt             time.Time // no default
+	CategoryId            string
+	ChannelId             string
+	DefaultAudioLanguage  string
+	DefaultLanguage       string
+	LiveBroadcastContent  string
+	Description           string // no default
+	Title                 string // no default
+	Tags                  []string
+	TruncatedFrom         int // length of the description in bytes before it was truncated, or zero
+	Localizations         map[string]youtube.VideoLocalization
+	MissingLanguages      []string // languages in the languages setting with no translation
+	RecordingLocation     *youtube.GeoPoint
+	RecordingDate         time.Time
+	Status                StatusFields
+	Hash                  string // hash of the rendered fields, see hash
 }
 
 func DefaultYoutubeFields() YoutubeFields {
 	return YoutubeFields{
-		PrivacyStatus:        "private",
-		CategoryId:           "19",
-		ChannelId:            "UCFDggPICIlCHp3iOWMYt8cg",
-		DefaultAudioLanguage: "en",
-		DefaultLanguage:      "en",
-		LiveBroadcastContent: "none",
+		PrivacyStatus:         "private",
+		ReleasedPrivacyStatus: "public",
+		CategoryId:            "19",
+		ChannelId:             "UCFDggPICIlCHp3iOWMYt8cg",
+		DefaultAudioLanguage:  "en",
+		DefaultLanguage:       "en",
+		LiveBroadcastContent:  "none",
 	}
 }
 
+// youtubeFields returns the default fields for the item. The category_id, channel_id, default_language,
+// default_audio_language, privacy_status and released_privacy_status settings override the values in
+// DefaultYoutubeFields.
+func (item *Item) youtubeFields() (YoutubeFields, error) {
+	fields := DefaultYoutubeFields()
+	for _, setting := range []struct {
+		name  string
+		value *string
+	}{
+		{"category_id", &fields.CategoryId},
+		{"channel_id", &fields.ChannelId},
+		{"default_language", &fields.DefaultLanguage},
+		{"default_audio_language", &fields.DefaultAudioLanguage},
+		{"privacy_status", &fields.PrivacyStatus},
+		{"released_privacy_status", &fields.ReleasedPrivacyStatus},
+	} {
+		if !item.Setting(setting.name).Empty() {
+			*setting.value = strings.TrimSpace(item.Setting(setting.name).String())
+		}
+	}
+	for name, value := range map[string]string{"privacy_status": fields.PrivacyStatus, "released_privacy_status": fields.ReleasedPrivacyStatus} {
+		if value != "private" && value != "unlisted" && value != "public" {
+			return YoutubeFields{}, fmt.Errorf("%s must be private, unlisted or public, found %q", name, value)
+		}
+	}
+	return fields, nil
+}
+
+// Released returns true if the PublishAt time has passed.
+func (y *YoutubeFields) Released() bool {
+	return time.Now().After(y.PublishAt)
+}
+
+// privacy returns the privacy status the video should have now.
+func (y *YoutubeFields) privacy() string {
+	if y.Released() {
+		return y.ReleasedPrivacyStatus
+	}
+	return y.PrivacyStatus
+}
+
+// scheduled returns true if YouTube should publish the video at the PublishAt time. YouTube can only
+// schedule a private video to become public, so other videos are changed when the tool runs after the
+// PublishAt time.
+func (y *YoutubeFields) scheduled() bool {
+	return !y.Released() && y.PrivacyStatus == "private" && y.ReleasedPrivacyStatus == "public"
+}
+
 type Change struct {
 	Before, After string
 }
 type Changes struct {
 	Changed                                            bool
+	Parts                                              map[string]bool // the API parts that changed
 	PrivacyStatus, PublishAt, Description, Title, Tags Change
+	Recording, Status                                  Change
+	Localizations                                      map[string]Change
+}
+
+// unchanged clears the changes, so nothing is sent and the preview shows every field as unchanged.
+func (c *Changes) unchanged() {
+	c.Changed, c.Parts = false, nil
+	for _, change := range []*Change{&c.PrivacyStatus, &c.PublishAt, &c.Description, &c.Title, &c.Tags, &c.Recording, &c.Status} {
+		change.Before = change.After
+	}
+	for lang, change := range c.Localizations {
+		change.Before = change.After
+		c.Localizations[lang] = change
+	}
+}
+
+// update returns the parts that changed, and a copy of the video with only those parts, so parts that
+// haven't changed aren't sent.
+func (c *Changes) update(video *youtube.Video) ([]string, *youtube.Video) {
+	update := &youtube.Video{Id: video.Id}
+	var parts []string
+	for _, part := range []string{"snippet", "localizations", "status", "recordingDetails", "paidProductPlacementDetails"} {
+		// localizations can only be updated with the snippet, because they need snippet.defaultLanguage
+		if !c.Parts[part] && !(part == "snippet" && c.Parts["localizations"]) {
+			continue
+		}
+		parts = append(parts, part)
+		switch part {
+		case "snippet":
+			update.Snippet = video.Snippet
+		case "localizations":
+			update.Localizations = video.Localizations
+		case "status":
+			update.Status = video.Status
+		case "recordingDetails":
+			update.RecordingDetails = video.RecordingDetails
+		case "paidProductPlacementDetails":
+			update.PaidProductPlacementDetails = video.PaidProductPlacementDetails
+		}
+	}
+	return parts, update
+}
+
+// values returns the fields that changed, for the plan.
+func (c *Changes) values() map[string]Change {
+	values := map[string]Change{}
+	for name, change := range map[string]Change{
+		"privacy_status": c.PrivacyStatus,
+		"publish_at":     c.PublishAt,
+		"description":    c.Description,
+		"title":          c.Title,
+		"tags":           c.Tags,
+		"recording":      c.Recording,
+		"status":         c.Status,
+	} {
+		if change.Before != change.After {
+			values[name] = change
+		}
+	}
+	for lang, change := range c.Localizations {
+		if change.Before != change.After {
+			values["localization."+lang] = change
+		}
+	}
+	return values
+}
+
+// change records that a part of the video changed.
+func (c *Changes) change(part string) {
+	c.Changed = true
+	if c.Parts == nil {
+		c.Parts = map[string]bool{}
+	}
+	c.Parts[part] = true
 }
 
 func (y *YoutubeFields) Apply(video *youtube.Video) Changes {
@@ -437,59 +775,57 @@ func (y *YoutubeFields) Apply(video *youtube.Video) Changes {
 		Tags:          Change{Before: strings.Join(video.Snippet.Tags, "\n")},
 	}
 
-	if time.Now().After(y.PublishAt) {
-		if video.Status.PrivacyStatus != "public" {
-			c.Changed = true
-			video.Status.PrivacyStatus = "public"
-		}
-		if video.Status.PublishAt != "" {
-			c.Changed = true
-			video.Status.PublishAt = ""
-		}
-	} else {
-		if video.Status.PrivacyStatus != y.PrivacyStatus {
-			c.Changed = true
-			video.Status.PrivacyStatus = y.PrivacyStatus
-		}
+	if privacy := y.privacy(); video.Status.PrivacyStatus != privacy {
+		c.change("status")
+		video.Status.PrivacyStatus = privacy
+	}
+	if y.scheduled() {
 		if !youtubeTimeEqual(video.Status.PublishAt, y.PublishAt) {
-			c.Changed = true
+			c.change("status")
 			video.Status.PublishAt = timeToYoutube(y.PublishAt)
 		}
+	} else if video.Status.PublishAt != "" {
+		c.change("status")
+		video.Status.PublishAt = ""
 	}
 	if video.Snippet.CategoryId != y.CategoryId {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.CategoryId = y.CategoryId
 	}
 	if video.Snippet.ChannelId != y.ChannelId {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.ChannelId = y.ChannelId
 	}
 	if video.Snippet.DefaultAudioLanguage != y.DefaultAudioLanguage {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.DefaultAudioLanguage = y.DefaultAudioLanguage
 	}
 	if video.Snippet.DefaultLanguage != y.DefaultLanguage {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.DefaultLanguage = y.DefaultLanguage
 	}
 	if video.Snippet.LiveBroadcastContent != y.LiveBroadcastContent {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.LiveBroadcastContent = y.LiveBroadcastContent
 	}
 	if video.Snippet.Description != y.Description {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.Description = y.Description
 	}
 	if video.Snippet.Title != y.Title {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.Title = y.Title
 	}
 
 	if !slicesEqualUnordered(video.Snippet.Tags, y.Tags) {
-		c.Changed = true
+		c.change("snippet")
 		video.Snippet.Tags = y.Tags
 	}
 
+	y.applyLocalizations(video, &c)
+	y.applyRecordingDetails(video, &c)
+	y.applyStatus(video, &c)
+
 	c.PrivacyStatus.After = video.Status.PrivacyStatus
 	c.PublishAt.After = video.Status.PublishAt
 	c.Description.After = video.Snippet.Description
@@ -531,7 +867,16 @@ func slicesEqualUnordered(slice1, slice2 []string) bool {
 }
 
 func timeToYoutube(t time.Time) string {
-	return strings.TrimSuffix(t.Format(time.RFC3339), "Z") + ".0Z"
+	return strings.TrimSuffix(t.UTC().Format(time.RFC3339), "Z") + ".0Z"
+}
+
+// youtubeTimeIn formats a YouTube time in loc for the preview sheet.
+func youtubeTimeIn(s string, loc *time.Location) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil || loc == nil {
+		return s
+	}
+	return t.In(loc).Format("2006-01-02 15:04:05 MST")
 }
 
 func youtubeTimeEqual(s string, t time.Time) bool {
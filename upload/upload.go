@@ -27,6 +27,17 @@ type Service struct {
 	YoutubePlaylists     map[string]*youtube.Playlist
 	VideoPreviewData     map[*Item]map[string]any
 	PlaylistPreviewData  map[HasPlaylist]map[string]any
+	Problems             []*Problem
+	Snapshots            map[string]*SheetSnapshot
+	Refresh              bool // Refresh ignores the cached sheet snapshots
+	Force                bool // Force overwrites fields edited in both the sheet and YouTube Studio
+	SyncState            *SyncState
+	RecentUploads        []*youtube.PlaylistItem // cached by findUploadedVideo
+	Quota                *QuotaUsage
+	Planning             *Plan  // operations are recorded in the plan instead of carried out
+	RunId                string // identifies the operations of this run in the journal
+	UploadSlots          int    // uploads that fit in the quota left today, or -1 if they aren't limited
+	DeferredUploads      int    // uploads left for the next run because they didn't fit in the quota
 }
 
 func New(channelId string) *Service {
@@ -38,13 +49,15 @@ func New(channelId string) *Service {
 	s.YoutubePlaylists = map[string]*youtube.Playlist{}
 	s.VideoPreviewData = map[*Item]map[string]any{}
 	s.PlaylistPreviewData = map[HasPlaylist]map[string]any{}
+	s.Snapshots = map[string]*SheetSnapshot{}
+	s.UploadSlots = -1
 
 	s.ChannelId = channelId
 
 	return s
 }
 
-func (s *Service) Start(ctx context.Context) error {
+func (s *Service) Start(ctx context.Context) (err error) {
 
 	if s.ChannelId == "" {
 		return fmt.Errorf("channel id is empty, use NewService to create a new *Service")
@@ -74,7 +87,7 @@ func (s *Service) Start(ctx context.Context) error {
 	}
 
 	// RESUME UPLOADER
-	{
+	if s.Planning == nil {
 		if err := s.ResumePartialUpload(ctx); err != nil {
 			return fmt.Errorf("unable to resume upload: %w", err)
 		}
@@ -82,40 +95,55 @@ func (s *Service) Start(ctx context.Context) error {
 
 	// GET SHEET DATA
 	{
-		if err := s.GetSheetData(nil, "global", "expedition"); err != nil {
-			return fmt.Errorf("unable to get global / expedition sheet data: %w", err)
-		}
-
-		if err := s.ParseGlobal(); err != nil {
-			return fmt.Errorf("unable to parse global: %w", err)
-		}
-
-		if err := s.ParseExpeditions(); err != nil {
-			return fmt.Errorf("unable to parse expeditions: %w", err)
-		}
-
-		if err := s.GetAllSheetsData(); err != nil {
-			return fmt.Errorf("unable to get sheets data: %w", err)
+		if err := s.LoadSheets(); err != nil {
+			return err
 		}
 
-		if err := s.ParseSections(); err != nil {
-			return fmt.Errorf("unable to parse sections: %w", err)
+		if s.Planning != nil {
+			// a plan lists the changes a production run would make
+			s.Global.Production = true
 		}
+	}
 
-		if err := s.ParseItems(); err != nil {
-			return fmt.Errorf("unable to parse items: %w", err)
+	// STOP CLEANLY WHEN OUT OF QUOTA
+	{
+		// deferred before the problems are written, so it runs after
+		defer func() {
+			// a plan that stopped early would be incomplete, so the error is returned and it isn't written
+			if isQuotaError(err) && s.Planning == nil {
+				err = s.stopForQuota(err)
+			}
+		}()
+
+		if err := s.printQuota(); err != nil {
+			return fmt.Errorf("reading quota usage: %w", err)
 		}
+	}
 
-		if err := s.ParseTemplates(); err != nil {
-			return fmt.Errorf("unable to parse templates: %w", err)
+	// WRITE PROBLEMS TO SHEET
+	{
+		defer func() {
+			if problemsErr := s.WriteProblems(); problemsErr != nil && err == nil {
+				err = fmt.Errorf("unable to write problems: %w", problemsErr)
+			}
+		}()
+
+		if len(s.Problems) > 0 {
+			return fmt.Errorf("found %d problems, see problems sheet", len(s.Problems))
 		}
+	}
 
-		if err := s.ParseLinkedData(); err != nil {
-			return fmt.Errorf("unable to parse linked data: %w", err)
+	// IMPORT ROUTES FROM GPX FILES
+	{
+		if err := s.ImportRoutes(); err != nil {
+			return fmt.Errorf("unable to import routes: %w", err)
 		}
+	}
 
+	// UPDATE VIDEO TITLES
+	{
 		if err := s.UpdateVideoTitles(); err != nil {
-			return fmt.Errorf("unable to parse linked data: %w", err)
+			return fmt.Errorf("unable to update video titles: %w", err)
 		}
 	}
 
@@ -164,13 +192,24 @@ func (s *Service) Start(ctx context.Context) error {
 		}
 	}
 
+	// VALIDATE YOUTUBE METADATA
+	{
+		if err := s.ValidateVideos(); err != nil {
+			return fmt.Errorf("validating videos: %w", err)
+		}
+	}
+
 	// UPLOAD TO YOUTUBE
 	{
+		if err := s.scheduleUploads(); err != nil {
+			return fmt.Errorf("scheduling uploads: %w", err)
+		}
+
 		if err := s.CreateOrUpdateVideos(ctx); err != nil {
 			return fmt.Errorf("updating videos: %w", err)
 		}
 
-		if err := s.CreateOrUpdatePlaylists(); err != nil {
+		if err := s.CreateOrUpdatePlaylists(ctx); err != nil {
 			return fmt.Errorf("updating playlists: %w", err)
 		}
 	}
@@ -188,11 +227,61 @@ func (s *Service) Start(ctx context.Context) error {
 
 	// UPDATE THUMBNAILS
 	{
-		if err := s.UpdateThumbnails(); err != nil {
+		if err := s.UpdateThumbnails(ctx); err != nil {
 			return fmt.Errorf("updating thumbnails: %w", err)
 		}
 	}
 
+	// the deferred uploads are recorded in the checkpoint
+	if s.DeferredUploads > 0 {
+		return &QuotaError{Method: "videos.insert", Reason: fmt.Sprintf("%d uploads deferred to the next run", s.DeferredUploads)}
+	}
+
+	if len(s.Problems) > 0 {
+		return fmt.Errorf("found %d problems, see problems sheet", len(s.Problems))
+	}
+
+	return nil
+}
+
+// LoadSheets gets the data from the global, expedition and processed expedition sheets, and parses it.
+func (s *Service) LoadSheets() error {
+	if err := s.GetSheetData(nil, "global", "expedition"); err != nil {
+		return fmt.Errorf("unable to get global / expedition sheet data: %w", err)
+	}
+
+	if err := s.ParseGlobal(); err != nil {
+		return fmt.Errorf("unable to parse global: %w", err)
+	}
+
+	if err := s.ParseExpeditions(); err != nil {
+		return fmt.Errorf("unable to parse expeditions: %w", err)
+	}
+
+	if err := s.GetAllSheetsData(); err != nil {
+		return fmt.Errorf("unable to get sheets data: %w", err)
+	}
+
+	if err := s.ParseSections(); err != nil {
+		return fmt.Errorf("unable to parse sections: %w", err)
+	}
+
+	if err := s.ParseItems(); err != nil {
+		return fmt.Errorf("unable to parse items: %w", err)
+	}
+
+	if err := s.ParseChapters(); err != nil {
+		return fmt.Errorf("unable to parse chapters: %w", err)
+	}
+
+	if err := s.ParseTemplates(); err != nil {
+		return fmt.Errorf("unable to parse templates: %w", err)
+	}
+
+	if err := s.ParseLinkedData(); err != nil {
+		return fmt.Errorf("unable to parse linked data: %w", err)
+	}
+
 	return nil
 }
 